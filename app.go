@@ -0,0 +1,414 @@
+package misc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type (
+	// App -- application lifecycle state. The package level functions (StopApp, Exit, AddFinalizer etc.) use the default instance
+	App struct {
+		started      int32
+		exitLaunched int32
+		initialized  int32
+
+		exitCode atomic.Int32
+		exitName atomic.Pointer[string] // registered name of the exit code given to StopStatus
+
+		interrupts atomic.Int32 // SIGINT received, the repeated one forces the exit
+
+		exitTrigger chan struct{}
+
+		ctx       context.Context
+		ctxCancel context.CancelCauseFunc
+
+		finalizers      []exitElement
+		finalizersMutex sync.RWMutex
+
+		timeoutsMutex      sync.RWMutex
+		terminationTimeout time.Duration
+		killingTimeout     time.Duration
+
+		exitFunc atomic.Pointer[func(code int)]
+		clockPtr atomic.Pointer[Clock]
+
+		diagnostics *DiagnosticsOptions
+
+		lifecycle lifecycle
+		drain     drain
+		workers   workers
+		jobs      jobs
+	}
+
+	// Clock -- time source of the application lifecycle
+	Clock interface {
+		Now() time.Time
+		After(d time.Duration) <-chan time.Time
+	}
+
+	realClock struct{}
+
+	// StopCause -- cause of the application context cancellation, available through context.Cause
+	StopCause struct {
+		Code int
+		// Registered name of the code given to StopStatus, "" if the application is stopped by the code
+		Name   string
+		Reason string
+	}
+)
+
+var (
+	defaultApp atomic.Pointer[App]
+)
+
+func init() {
+	defaultApp.Store(NewApp())
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Now --
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// After --
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Error --
+func (c *StopCause) Error() string {
+	if c.Reason == "" {
+		return fmt.Sprintf("application stopped with code %d", c.Code)
+	}
+
+	return fmt.Sprintf("application stopped with code %d: %s", c.Code, c.Reason)
+}
+
+// Is -- errors.Is(cause, context.Canceled) is true
+func (c *StopCause) Is(target error) bool {
+	return target == context.Canceled
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// NewApp -- create a new application lifecycle state
+func NewApp() *App {
+	a := &App{
+		started:            1,
+		exitTrigger:        make(chan struct{}),
+		finalizers:         make([]exitElement, 0),
+		terminationTimeout: 5 * time.Second,
+		killingTimeout:     5 * time.Second,
+	}
+
+	a.SetExitFunc(os.Exit)
+	a.SetClock(realClock{})
+
+	a.ctx, a.ctxCancel = context.WithCancelCause(context.Background())
+	a.lifecycle.since = a.clock().Now()
+	a.drain.init()
+
+	return a
+}
+
+// DefaultApp -- the instance used by the package level functions
+func DefaultApp() *App {
+	return defaultApp.Load()
+}
+
+// SetDefaultApp -- replace the instance used by the package level functions, returns the previous one.
+// Useful for the tests: SetDefaultApp(NewApp()) gives a fresh application state
+func SetDefaultApp(a *App) (prev *App) {
+	return defaultApp.Swap(a)
+}
+
+// SetExitFunc -- replace os.Exit, returns the previous function. Safe to call at any time
+func (a *App) SetExitFunc(f func(code int)) (prev func(code int)) {
+	if p := a.exitFunc.Swap(&f); p != nil {
+		prev = *p
+	}
+	return
+}
+
+// SetClock -- replace the time source, returns the previous one. Safe to call at any time, but the already started waits use the previous clock
+func (a *App) SetClock(c Clock) (prev Clock) {
+	if p := a.clockPtr.Swap(&c); p != nil {
+		prev = *p
+	}
+	return
+}
+
+func (a *App) clock() Clock {
+	return *a.clockPtr.Load()
+}
+
+func (a *App) exit(code int) {
+	(*a.exitFunc.Load())(code)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetExitTimeouts -- set timeouts of the termination (from StopApp to Exit) and killing (from Exit to the forced exit)
+func (a *App) SetExitTimeouts(newTerminationTimeout time.Duration, newKillingTimeout time.Duration) (prevTerminationTimeout time.Duration, prevKillingTimeout time.Duration) {
+	a.timeoutsMutex.Lock()
+	defer a.timeoutsMutex.Unlock()
+
+	prevTerminationTimeout, prevKillingTimeout = a.terminationTimeout, a.killingTimeout
+
+	if newTerminationTimeout > 0 {
+		a.terminationTimeout = newTerminationTimeout
+	}
+
+	if newKillingTimeout > 0 {
+		a.killingTimeout = newKillingTimeout
+	}
+
+	return
+}
+
+func (a *App) exitTimeouts() (terminationTimeout time.Duration, killingTimeout time.Duration) {
+	a.timeoutsMutex.RLock()
+	defer a.timeoutsMutex.RUnlock()
+
+	return a.terminationTimeout, a.killingTimeout
+}
+
+func (a *App) killer() {
+	terminationTimeout, killingTimeout := a.exitTimeouts()
+
+	<-a.clock().After(terminationTimeout)
+	logMessage(LogLevelNotice, "Application shutdown timeout. Force termination.")
+	a.dumpDiagnosticsOnTimeout()
+	go a.Exit()
+
+	<-a.clock().After(killingTimeout)
+	logMessage(LogLevelNotice, "Application termination timeout. Force killing.")
+	a.Kill()
+}
+
+// Kill -- immediate exit with the current exit code, finalizers are not called
+func (a *App) Kill() {
+	a.exit(a.ExitCode())
+}
+
+// Stop -- set exit code and raise application stop
+func (a *App) Stop(code int) {
+	a.StopEx(code, "")
+}
+
+// StopEx -- set exit code and raise application stop with the reason
+func (a *App) StopEx(code int, reason string) {
+	a.stop(code, "", reason)
+}
+
+// StopStatus -- raise application stop with the exit code registered with the name (see RegisterExitStatus).
+// The name is logged instead of all names sharing the code
+func (a *App) StopStatus(name string, reason string) error {
+	code, ok := ExitStatusByName(name)
+	if !ok {
+		return fmt.Errorf(`unknown exit code name "%s"`, name)
+	}
+
+	a.stop(int(code), name, reason)
+	return nil
+}
+
+func (a *App) stop(code int, name string, reason string) {
+	if atomic.AddInt32(&a.started, -1) == 0 {
+		a.exitCode.Store(int32(code))
+		a.exitName.Store(&name)
+
+		if reason == "" {
+			logMessage(LogLevelDebug, "Set application exit code %s", a.exitStatus())
+		} else {
+			logMessage(LogLevelDebug, "Set application exit code %s: %s", a.exitStatus(), reason)
+		}
+
+		close(a.exitTrigger)
+		a.ctxCancel(&StopCause{Code: code, Name: name, Reason: reason})
+		a.setState(StateDraining)
+
+		go a.killer()
+	}
+}
+
+// WaitingForStop --
+func (a *App) WaitingForStop() {
+	<-a.exitTrigger
+}
+
+// Stopped -- channel closed when the application stops
+func (a *App) Stopped() <-chan struct{} {
+	return a.exitTrigger
+}
+
+// Context -- root context of the application, it is canceled when the application stops
+func (a *App) Context() context.Context {
+	return a.ctx
+}
+
+// StopCause -- cause of the application stop, nil if the application is not stopped yet
+func (a *App) StopCause() *StopCause {
+	var c *StopCause
+	if errors.As(context.Cause(a.ctx), &c) {
+		return c
+	}
+
+	return nil
+}
+
+// Exit -- exit application
+func (a *App) Exit() {
+	if atomic.AddInt32(&a.exitLaunched, 1) == 1 {
+		if a.Started() {
+			a.Stop(0)
+		}
+
+		logMessage(LogLevelInfo, "Try to finish application with code %s", a.exitStatus())
+
+		a.waitDrain()
+
+		a.setState(StateStopping)
+
+		if logFinalizersReport(a.callFinalizers(a.ExitCode())) && a.ExitCode() != ExPanic {
+			prev := a.exitStatus()
+			name := "ExPanic"
+			a.exitCode.Store(ExPanic)
+			a.exitName.Store(&name)
+			logMessage(LogLevelInfo, "Exit code changed from %s to %s due to the finalizer panic", prev, a.exitStatus())
+		}
+
+		logMessage(LogLevelInfo, "Application finished with code %s", a.exitStatus())
+		a.setState(StateStopped)
+		a.exit(a.ExitCode())
+	}
+}
+
+// Started -- is application started?
+func (a *App) Started() bool {
+	return atomic.LoadInt32(&a.started) > 0
+}
+
+// SetInitialized -- mark application as initialized
+func (a *App) SetInitialized() {
+	atomic.StoreInt32(&a.initialized, 1)
+	a.updateState(func(l *lifecycle) {})
+}
+
+// Initialized -- is application initialized?
+func (a *App) Initialized() bool {
+	return atomic.LoadInt32(&a.initialized) > 0
+}
+
+// ExitCode -- get current exit code
+func (a *App) ExitCode() int {
+	return int(a.exitCode.Load())
+}
+
+// exitStatus -- current exit code for the log, with the name given to StopStatus if any
+func (a *App) exitStatus() string {
+	s := ExitStatus(a.ExitCode())
+	if name := a.exitName.Load(); name != nil && *name != "" {
+		return s.NamedString(*name)
+	}
+
+	return s.String()
+}
+
+// Sleep -- sleep the duration, returns false if the application stopped
+func (a *App) Sleep(duration time.Duration) bool {
+	if !a.Started() {
+		return false
+	}
+
+	select {
+	case <-a.exitTrigger:
+		return false
+	case <-a.clock().After(duration):
+		return true
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetExitTimeouts --
+func SetExitTimeouts(newTerminationTimeout time.Duration, newKillingTimeout time.Duration) (prevTerminationTimeout time.Duration, prevKillingTimeout time.Duration) {
+	return DefaultApp().SetExitTimeouts(newTerminationTimeout, newKillingTimeout)
+}
+
+// StopApp -- set exit code and raise application stop
+func StopApp(code int) {
+	DefaultApp().Stop(code)
+}
+
+// StopAppEx -- set exit code and raise application stop with the reason
+func StopAppEx(code int, reason string) {
+	DefaultApp().StopEx(code, reason)
+}
+
+// StopAppStatus -- raise application stop with the exit code registered with the name, see App.StopStatus
+func StopAppStatus(name string, reason string) error {
+	return DefaultApp().StopStatus(name, reason)
+}
+
+// WaitingForStop --
+func WaitingForStop() {
+	DefaultApp().WaitingForStop()
+}
+
+// ApplicationStopped --
+func ApplicationStopped() <-chan struct{} {
+	return DefaultApp().Stopped()
+}
+
+// AppContext -- root context of the application, it is canceled when the application stops.
+// context.Cause(AppContext()) returns *StopCause with the exit code and the reason
+func AppContext() context.Context {
+	return DefaultApp().Context()
+}
+
+// AppStopCause -- cause of the application stop, nil if the application is not stopped yet
+func AppStopCause() *StopCause {
+	return DefaultApp().StopCause()
+}
+
+// Exit -- exit application
+func Exit() {
+	DefaultApp().Exit()
+}
+
+// AppStarted -- is application started?
+func AppStarted() bool {
+	return DefaultApp().Started()
+}
+
+func Initialized() {
+	DefaultApp().SetInitialized()
+}
+
+func AppInitialized() bool {
+	return DefaultApp().Initialized()
+}
+
+// ExitCode -- get current exit code
+func ExitCode() int {
+	return DefaultApp().ExitCode()
+}
+
+// Sleep --
+func Sleep(duration time.Duration) bool {
+	return DefaultApp().Sleep(duration)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,174 @@
+package misc
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type (
+	// BuildInfo -- build information: values from ldflags (see AppVersion etc.) merged with debug.ReadBuildInfo
+	BuildInfo struct {
+		AppName       string            `json:"appName"`
+		Version       string            `json:"version"`
+		Tags          string            `json:"tags,omitempty"`
+		Copyright     string            `json:"copyright,omitempty"`
+		BuildTime     time.Time         `json:"buildTime,omitzero"`
+		GoVersion     string            `json:"goVersion"`
+		OS            string            `json:"os"`
+		Arch          string            `json:"arch"`
+		Path          string            `json:"path,omitempty"`
+		Module        string            `json:"module,omitempty"`
+		ModuleVersion string            `json:"moduleVersion,omitempty"`
+		VCS           string            `json:"vcs,omitempty"`
+		Revision      string            `json:"revision,omitempty"`
+		Modified      bool              `json:"modified,omitempty"`
+		CommitTime    time.Time         `json:"commitTime,omitzero"`
+		Settings      map[string]string `json:"settings,omitempty"`
+		Deps          []BuildDep        `json:"deps,omitempty"`
+	}
+
+	// BuildDep -- dependency module
+	BuildDep struct {
+		Path    string `json:"path"`
+		Version string `json:"version"`
+		Sum     string `json:"sum,omitempty"`
+		Replace string `json:"replace,omitempty"`
+	}
+)
+
+var (
+	buildInfo = sync.OnceValue(makeBuildInfo)
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// GetBuildInfo -- build information. The result is shared, don't modify it
+func GetBuildInfo() *BuildInfo {
+	return buildInfo()
+}
+
+func makeBuildInfo() *BuildInfo {
+	b := &BuildInfo{
+		AppName:   AppName(),
+		Version:   AppVersion(),
+		Tags:      AppTags(),
+		Copyright: Copyright(),
+		BuildTime: BuildTimeTS(),
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return b
+	}
+
+	b.GoVersion = bi.GoVersion
+	b.Path = bi.Path
+	b.Module = bi.Main.Path
+	b.ModuleVersion = bi.Main.Version
+
+	b.Settings = make(map[string]string, len(bi.Settings))
+	for _, s := range bi.Settings {
+		b.Settings[s.Key] = s.Value
+
+		switch s.Key {
+		case "vcs":
+			b.VCS = s.Value
+		case "vcs.revision":
+			b.Revision = s.Value
+		case "vcs.modified":
+			b.Modified = s.Value == "true"
+		case "vcs.time":
+			b.CommitTime, _ = time.Parse(time.RFC3339, s.Value)
+		}
+	}
+
+	b.Deps = make([]BuildDep, 0, len(bi.Deps))
+	for _, d := range bi.Deps {
+		dep := BuildDep{
+			Path:    d.Path,
+			Version: d.Version,
+			Sum:     d.Sum,
+		}
+		if d.Replace != nil {
+			dep.Replace = d.Replace.Path + " " + d.Replace.Version
+		}
+		b.Deps = append(b.Deps, dep)
+	}
+
+	if appVersion == "debug" {
+		// not set by ldflags
+		switch {
+		case b.ModuleVersion != "" && b.ModuleVersion != "(devel)":
+			b.Version = b.ModuleVersion
+		case b.Revision != "":
+			b.Version = b.Revision[:min(12, len(b.Revision))]
+			if b.Modified {
+				b.Version += "-dirty"
+			}
+		}
+	}
+
+	if b.BuildTime.IsZero() {
+		b.BuildTime = b.CommitTime
+	}
+
+	return b
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// String -- text suitable for the --version output
+func (b *BuildInfo) String() string {
+	var s strings.Builder
+
+	fmt.Fprintf(&s, "%s %s"+EOS, b.AppName, b.Version)
+
+	if b.Tags != "" {
+		fmt.Fprintf(&s, "Tags:      %s"+EOS, b.Tags)
+	}
+
+	if !b.BuildTime.IsZero() {
+		fmt.Fprintf(&s, "Built:     %s"+EOS, b.BuildTime.Format(DateTimeFormatRev))
+	}
+
+	fmt.Fprintf(&s, "Go:        %s %s/%s"+EOS, b.GoVersion, b.OS, b.Arch)
+
+	if b.Module != "" {
+		fmt.Fprintf(&s, "Module:    %s %s"+EOS, b.Module, b.ModuleVersion)
+	}
+
+	if b.Revision != "" {
+		modified := ""
+		if b.Modified {
+			modified = " (modified)"
+		}
+		fmt.Fprintf(&s, "Revision:  %s%s", b.Revision, modified)
+		if !b.CommitTime.IsZero() {
+			fmt.Fprintf(&s, " at %s", b.CommitTime.Format(DateTimeFormatRev))
+		}
+		s.WriteString(EOS)
+	}
+
+	if b.Copyright != "" {
+		s.WriteString(b.Copyright + EOS)
+	}
+
+	return s.String()
+}
+
+// JSON -- JSON suitable for the /version endpoint
+func (b *BuildInfo) JSON() ([]byte, error) {
+	return json.Marshal(b)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,311 @@
+package misc
+
+import (
+	"cmp"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type (
+	// CallStackFrame -- call stack element
+	CallStackFrame struct {
+		FuncName string
+		FileName string
+		Line     int
+	}
+
+	// CallStackOptions -- frames filter
+	CallStackOptions struct {
+		// Maximal number of the returned frames, 0 -- unlimited
+		MaxDepth int
+		// Skip the runtime, internal and testing frames
+		SkipRuntime bool
+		// Skip the standard library frames (packages with the first import path element without a dot, except main)
+		SkipStdlib bool
+		// Skip the frames of the functions with the name prefixes ("github.com/alrusov/misc." etc.)
+		SkipPrefixes []string
+	}
+
+	// FileNameMode -- file names format
+	FileNameMode int
+
+	// CallStackFormat -- FormatCallStackEx options
+	CallStackFormat struct {
+		FileNames FileNameMode
+		// Function names without the package path: "misc.GetCallStack"
+		ShortFuncNames bool
+		// "function file:line" instead of the function and the file on separated lines
+		SingleLine bool
+	}
+)
+
+// File names formats
+const (
+	// FileNameFull -- as recorded by the compiler
+	FileNameFull FileNameMode = iota
+	// FileNameShort -- base name only
+	FileNameShort
+	// FileNameModule -- relative to the module root, the standard library files are relative to GOROOT/src
+	FileNameModule
+)
+
+const (
+	callStackBufSize = 64
+)
+
+var (
+	callStackPCPool = sync.Pool{
+		New: func() any {
+			buf := make([]uintptr, callStackBufSize)
+			return &buf
+		},
+	}
+
+	// pc -> []CallStackFrame. The number of the pc values is limited by the code size
+	callStackCache sync.Map
+
+	modulePaths = sync.OnceValue(makeModulePaths)
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// GetCallStack -- get call stack. shift 0 -- the caller of GetCallStack
+func GetCallStack(shift int) []CallStackFrame {
+	return GetCallStackEx(shift+1, nil)
+}
+
+// GetCallStackEx -- get the filtered call stack. shift 0 -- the caller of GetCallStackEx.
+// Inlined functions are reported as separated frames, symbolized frames are cached
+func GetCallStackEx(shift int, opts *CallStackOptions) []CallStackFrame {
+	if opts == nil {
+		opts = &CallStackOptions{}
+	}
+
+	bufp := callStackPCPool.Get().(*[]uintptr)
+	defer callStackPCPool.Put(bufp)
+
+	pc := *bufp
+	var n int
+	for {
+		n = runtime.Callers(2+shift, pc)
+		if n < len(pc) {
+			break
+		}
+		// probably truncated
+		pc = make([]uintptr, len(pc)*2)
+	}
+
+	if cap(pc) > cap(*bufp) && cap(pc) <= 16*callStackBufSize {
+		*bufp = pc
+	}
+
+	capacity := n
+	if opts.MaxDepth > 0 {
+		capacity = min(n, opts.MaxDepth)
+	}
+	ret := make([]CallStackFrame, 0, capacity)
+
+	for _, p := range pc[:n] {
+		for _, frame := range symbolizePC(p) {
+			if opts.skip(frame.FuncName) {
+				continue
+			}
+
+			ret = append(ret, frame)
+			if opts.MaxDepth > 0 && len(ret) >= opts.MaxDepth {
+				return ret
+			}
+		}
+	}
+
+	return ret
+}
+
+func symbolizePC(pc uintptr) []CallStackFrame {
+	if v, exists := callStackCache.Load(pc); exists {
+		return v.([]CallStackFrame)
+	}
+
+	var list []CallStackFrame
+
+	frames := runtime.CallersFrames([]uintptr{pc})
+	for {
+		f, more := frames.Next()
+
+		frame := CallStackFrame{
+			FuncName: f.Function,
+			FileName: f.File,
+			Line:     f.Line,
+		}
+		if frame.FuncName == "" {
+			frame.FuncName = "?"
+		}
+		list = append(list, frame)
+
+		if !more {
+			break
+		}
+	}
+
+	callStackCache.Store(pc, list)
+	return list
+}
+
+func (opts *CallStackOptions) skip(funcName string) bool {
+	if opts.SkipRuntime || opts.SkipStdlib {
+		pkg := funcPackage(funcName)
+
+		if opts.SkipRuntime {
+			switch {
+			case pkg == "runtime", pkg == "testing",
+				strings.HasPrefix(pkg, "runtime/"), strings.HasPrefix(pkg, "internal/"):
+				return true
+			}
+		}
+
+		if opts.SkipStdlib && isStdlibPackage(pkg) {
+			return true
+		}
+	}
+
+	for _, prefix := range opts.SkipPrefixes {
+		if strings.HasPrefix(funcName, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// funcPackage -- package path of the full function name: "net/http.(*Server).Serve" -> "net/http"
+func funcPackage(funcName string) string {
+	slash := strings.LastIndexByte(funcName, '/')
+	dot := strings.IndexByte(funcName[slash+1:], '.')
+	if dot < 0 {
+		return funcName
+	}
+	return funcName[:slash+1+dot]
+}
+
+func isStdlibPackage(pkg string) bool {
+	if pkg == "main" {
+		return false
+	}
+
+	first, _, _ := strings.Cut(pkg, "/")
+	return !strings.Contains(first, ".")
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// GetFuncName -- name of the function from call stack, the runtime and testing frames are skipped. shortName -- the function only,
+// otherwise the chain from the outermost function: "main.main->main.run->misc.Test"
+func GetFuncName(shift int, shortName bool) string {
+	opts := &CallStackOptions{
+		SkipRuntime: true,
+	}
+	if shortName {
+		opts.MaxDepth = 1
+	}
+
+	stack := GetCallStackEx(shift+1, opts)
+
+	var b strings.Builder
+	for i := len(stack) - 1; i >= 0; i-- {
+		if i != len(stack)-1 {
+			b.WriteString("->")
+		}
+		b.WriteString(filepath.Base(stack[i].FuncName))
+	}
+
+	return b.String()
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// FormatCallStack -- call stack as a multiline string
+func FormatCallStack(stack []CallStackFrame) string {
+	return FormatCallStackEx(stack, nil)
+}
+
+// FormatCallStackEx -- call stack as a multiline string with the format options
+func FormatCallStackEx(stack []CallStackFrame, format *CallStackFormat) string {
+	if format == nil {
+		format = &CallStackFormat{}
+	}
+
+	var b strings.Builder
+
+	for i, frame := range stack {
+		if i != 0 {
+			b.WriteString(EOS)
+		}
+
+		if format.ShortFuncNames {
+			b.WriteString(filepath.Base(frame.FuncName))
+		} else {
+			b.WriteString(frame.FuncName)
+		}
+
+		if format.SingleLine {
+			b.WriteString(" ")
+		} else {
+			b.WriteString(EOS + "\t")
+		}
+
+		b.WriteString(formatFileName(frame, format.FileNames))
+		b.WriteString(":")
+		b.WriteString(strconv.Itoa(frame.Line))
+	}
+
+	return b.String()
+}
+
+func formatFileName(frame CallStackFrame, mode FileNameMode) string {
+	switch mode {
+	case FileNameShort:
+		return filepath.Base(frame.FileName)
+
+	case FileNameModule:
+		pkg := funcPackage(frame.FuncName)
+		base := filepath.Base(frame.FileName)
+
+		for _, m := range modulePaths() {
+			if pkg == m {
+				return base
+			}
+			if strings.HasPrefix(pkg, m+"/") {
+				return pkg[len(m)+1:] + "/" + base
+			}
+		}
+
+		return pkg + "/" + base
+
+	default:
+		return frame.FileName
+	}
+}
+
+// makeModulePaths -- main module and dependencies paths, the longest first
+func makeModulePaths() []string {
+	bi := GetBuildInfo()
+
+	list := make([]string, 0, len(bi.Deps)+1)
+	if bi.Module != "" {
+		list = append(list, bi.Module)
+	}
+	for _, d := range bi.Deps {
+		list = append(list, d.Path)
+	}
+
+	slices.SortFunc(list, func(a, b string) int { return cmp.Compare(len(b), len(a)) })
+	return list
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
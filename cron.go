@@ -0,0 +1,318 @@
+package misc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type (
+	// JobSchedule -- schedule of the periodic job
+	JobSchedule interface {
+		// Next -- next activation time after t, zero time -- never
+		Next(t time.Time) time.Time
+		String() string
+	}
+
+	// IntervalSchedule -- activation every Interval
+	IntervalSchedule struct {
+		Interval time.Duration
+	}
+
+	// CronSchedule -- cron expression: [second] minute hour day-of-month month day-of-week
+	CronSchedule struct {
+		spec     string
+		second   uint64
+		minute   uint64
+		hour     uint64
+		dom      uint64
+		month    uint64
+		dow      uint64
+		domStar  bool
+		dowStar  bool
+		location *time.Location
+	}
+
+	cronField struct {
+		min   int
+		max   int
+		names map[string]int
+	}
+)
+
+var (
+	cronSecond = cronField{0, 59, nil}
+	cronMinute = cronField{0, 59, nil}
+	cronHour   = cronField{0, 23, nil}
+	cronDom    = cronField{1, 31, nil}
+	cronMonth  = cronField{1, 12, map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	cronDow = cronField{0, 7, map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+
+	cronDescriptors = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// ParseJobSchedule -- interval ("1h30m", "1d", "@every 10m", see Interval2Duration) or cron expression (5 or 6 fields, @daily etc.)
+func ParseJobSchedule(spec string) (JobSchedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	if s, ok := strings.CutPrefix(spec, "@every "); ok {
+		d, err := Interval2Duration(s)
+		if err != nil {
+			return nil, err
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf(`bad interval "%s"`, s)
+		}
+		return &IntervalSchedule{Interval: d}, nil
+	}
+
+	if d, err := Interval2Duration(spec); err == nil && d > 0 {
+		return &IntervalSchedule{Interval: d}, nil
+	}
+
+	return ParseCron(spec, nil)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Next --
+func (s *IntervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(s.Interval)
+}
+
+// String --
+func (s *IntervalSchedule) String() string {
+	return "@every " + Duration2Interval(s.Interval)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// ParseCron -- parse cron expression, location nil -- location of the time passed to Next
+func ParseCron(spec string, location *time.Location) (*CronSchedule, error) {
+	src := strings.TrimSpace(spec)
+
+	expr := src
+	if d, exists := cronDescriptors[strings.ToLower(expr)]; exists {
+		expr = d
+	}
+
+	fields := strings.Fields(expr)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf(`cron "%s": 5 or 6 fields expected, got %d`, src, len(fields))
+	}
+
+	c := &CronSchedule{
+		spec:     src,
+		location: location,
+	}
+
+	defs := []struct {
+		f   *cronField
+		dst *uint64
+	}{
+		{&cronSecond, &c.second},
+		{&cronMinute, &c.minute},
+		{&cronHour, &c.hour},
+		{&cronDom, &c.dom},
+		{&cronMonth, &c.month},
+		{&cronDow, &c.dow},
+	}
+
+	for i, df := range defs {
+		v, err := df.f.parse(fields[i])
+		if err != nil {
+			return nil, fmt.Errorf(`cron "%s": field %d: %w`, src, i+1, err)
+		}
+		*df.dst = v
+	}
+
+	// 7 is sunday too
+	if c.dow&(1<<7) != 0 {
+		c.dow = c.dow&^(1<<7) | 1
+	}
+
+	c.domStar = fields[3] == "*" || fields[3] == "?"
+	c.dowStar = fields[5] == "*" || fields[5] == "?"
+
+	return c, nil
+}
+
+func (f *cronField) parse(s string) (bits uint64, err error) {
+	for _, part := range strings.Split(s, ",") {
+		step := 1
+
+		rng, stepS, hasStep := strings.Cut(part, "/")
+		if hasStep {
+			step, err = strconv.Atoi(stepS)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf(`bad step "%s"`, stepS)
+			}
+		}
+
+		from, to := f.min, f.max
+
+		switch {
+		case rng == "*" || rng == "?":
+		default:
+			fromS, toS, isRange := strings.Cut(rng, "-")
+
+			from, err = f.value(fromS)
+			if err != nil {
+				return
+			}
+
+			switch {
+			case isRange:
+				to, err = f.value(toS)
+				if err != nil {
+					return
+				}
+			case !hasStep:
+				to = from
+			}
+		}
+
+		if from > to {
+			return 0, fmt.Errorf(`bad range "%s"`, rng)
+		}
+
+		for i := from; i <= to; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+
+	return
+}
+
+func (f *cronField) value(s string) (int, error) {
+	if v, exists := f.names[strings.ToLower(s)]; exists {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf(`bad value "%s"`, s)
+	}
+
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf(`value %d is out of range [%d, %d]`, v, f.min, f.max)
+	}
+
+	return v, nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// String --
+func (c *CronSchedule) String() string {
+	return c.spec
+}
+
+// Next --
+func (c *CronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	if c.location != nil {
+		loc = c.location
+	}
+
+	t = t.In(loc)
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second()+1, 0, loc)
+
+	yearLimit := t.Year() + 5
+	added := false
+
+wrap:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for c.month&(1<<uint(t.Month())) == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 1, 0)
+		if t.Month() == time.January {
+			goto wrap
+		}
+	}
+
+	for !c.dayMatches(t) {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 0, 1)
+		if t.Day() == 1 {
+			goto wrap
+		}
+	}
+
+	for c.hour&(1<<uint(t.Hour())) == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+		}
+		t = t.Add(time.Hour)
+		if t.Hour() == 0 {
+			goto wrap
+		}
+	}
+
+	for c.minute&(1<<uint(t.Minute())) == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+		}
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto wrap
+		}
+	}
+
+	for c.second&(1<<uint(t.Second())) == 0 {
+		added = true
+		t = t.Add(time.Second)
+		if t.Second() == 0 {
+			goto wrap
+		}
+	}
+
+	return t
+}
+
+func (c *CronSchedule) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+
+	// both are restricted -- any of them
+	return dom || dow
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,189 @@
+package misc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type (
+	// DiagnosticsOptions -- when and where the diagnostics dump is written
+	DiagnosticsOptions struct {
+		// Signal triggering the dump, nil -- none
+		Signal os.Signal
+		// Write the dump on the termination timeout
+		OnTimeout bool
+		// Directory of the dump files (AbsPath prefixes are supported), "" -- the directory of the executable
+		Dir string
+		// Write the dump to the Logger instead of the file
+		ToLogger bool
+	}
+)
+
+var (
+	diagnosticsMutex sync.RWMutex
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// EnableDiagnostics -- enable the diagnostics dump
+func (a *App) EnableDiagnostics(opts DiagnosticsOptions) {
+	diagnosticsMutex.Lock()
+	prev := a.diagnostics
+	a.diagnostics = &opts
+	diagnosticsMutex.Unlock()
+
+	if prev != nil && prev.Signal != nil && prev.Signal != opts.Signal {
+		HandleSignal(prev.Signal, nil)
+	}
+
+	if opts.Signal != nil {
+		HandleSignal(opts.Signal,
+			func(sig os.Signal) {
+				a.DumpDiagnostics("signal " + sig.String())
+			},
+		)
+	}
+}
+
+// DisableDiagnostics -- disable the diagnostics dump
+func (a *App) DisableDiagnostics() {
+	diagnosticsMutex.Lock()
+	prev := a.diagnostics
+	a.diagnostics = nil
+	diagnosticsMutex.Unlock()
+
+	if prev != nil && prev.Signal != nil {
+		HandleSignal(prev.Signal, nil)
+	}
+}
+
+func (a *App) diagnosticsOptions() *DiagnosticsOptions {
+	diagnosticsMutex.RLock()
+	defer diagnosticsMutex.RUnlock()
+
+	return a.diagnostics
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// WriteDiagnostics -- write the diagnostics: application info, memory statistics, finalizers and stacks of all goroutines
+func (a *App) WriteDiagnostics(w io.Writer, reason string) (err error) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	now := a.clock().Now()
+
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "Application:  %s %s (%s)"+EOS, AppName(), AppVersion(), AppFullName())
+	fmt.Fprintf(&b, "Tags:         %s"+EOS, AppTags())
+	fmt.Fprintf(&b, "Build time:   %s"+EOS, BuildTime())
+	fmt.Fprintf(&b, "Go version:   %s"+EOS, runtime.Version())
+	fmt.Fprintf(&b, "Start time:   %s"+EOS, AppStartTime().Format(DateTimeFormatJSONTZ))
+	fmt.Fprintf(&b, "Dump time:    %s"+EOS, now.UTC().Format(DateTimeFormatJSONTZ))
+	fmt.Fprintf(&b, "Uptime:       %s"+EOS, now.Sub(AppStartTime()).Round(time.Millisecond))
+	fmt.Fprintf(&b, "Reason:       %s"+EOS, reason)
+	fmt.Fprintf(&b, "Started:      %t"+EOS, a.Started())
+	fmt.Fprintf(&b, "Exit code:    %d"+EOS, a.ExitCode())
+	fmt.Fprintf(&b, "Finalizers:   %s"+EOS, strings.Join(a.FinalizerNames(), ", "))
+	fmt.Fprintf(&b, "Goroutines:   %d"+EOS, runtime.NumGoroutine())
+	fmt.Fprintf(&b, "Memory:       Alloc=%d TotalAlloc=%d Sys=%d HeapAlloc=%d HeapInuse=%d HeapObjects=%d StackInuse=%d NumGC=%d"+EOS,
+		m.Alloc, m.TotalAlloc, m.Sys, m.HeapAlloc, m.HeapInuse, m.HeapObjects, m.StackInuse, m.NumGC)
+	b.WriteString(EOS)
+
+	stack := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(stack, true)
+		if n < len(stack) {
+			stack = stack[:n]
+			break
+		}
+		stack = make([]byte, 2*len(stack))
+	}
+	b.Write(stack)
+
+	_, err = w.Write(b.Bytes())
+	return
+}
+
+// DumpDiagnostics -- write the diagnostics to the file or to the Logger according to the options set by EnableDiagnostics
+func (a *App) DumpDiagnostics(reason string) (fileName string, err error) {
+	opts := a.diagnosticsOptions()
+	if opts == nil {
+		opts = &DiagnosticsOptions{}
+	}
+
+	if opts.ToLogger {
+		var b bytes.Buffer
+		a.WriteDiagnostics(&b, reason)
+		logMessage(LogLevelNotice, "Diagnostics dump:"+EOS+"%s", b.String())
+		return
+	}
+
+	dir := AppExecPath()
+	if opts.Dir != "" {
+		dir, err = AbsPath(opts.Dir)
+		if err != nil {
+			logMessage(LogLevelError, "Diagnostics dump: %s", err)
+			return
+		}
+	}
+
+	fileName = filepath.Join(dir, fmt.Sprintf("%s.%s.diag", AppName(), a.clock().Now().UTC().Format("20060102-150405.000")))
+
+	f, err := os.Create(fileName)
+	if err != nil {
+		logMessage(LogLevelError, "Diagnostics dump: %s", err)
+		return
+	}
+	defer f.Close()
+
+	err = a.WriteDiagnostics(f, reason)
+	if err != nil {
+		logMessage(LogLevelError, "Diagnostics dump: %s", err)
+		return
+	}
+
+	logMessage(LogLevelNotice, "Diagnostics dumped to %s", fileName)
+	return
+}
+
+func (a *App) dumpDiagnosticsOnTimeout() {
+	opts := a.diagnosticsOptions()
+	if opts != nil && opts.OnTimeout {
+		a.DumpDiagnostics("termination timeout")
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// EnableDiagnostics --
+func EnableDiagnostics(opts DiagnosticsOptions) {
+	DefaultApp().EnableDiagnostics(opts)
+}
+
+// DisableDiagnostics --
+func DisableDiagnostics() {
+	DefaultApp().DisableDiagnostics()
+}
+
+// WriteDiagnostics --
+func WriteDiagnostics(w io.Writer, reason string) error {
+	return DefaultApp().WriteDiagnostics(w, reason)
+}
+
+// DumpDiagnostics --
+func DumpDiagnostics(reason string) (fileName string, err error) {
+	return DefaultApp().DumpDiagnostics(reason)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
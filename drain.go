@@ -0,0 +1,114 @@
+package misc
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type (
+	drain struct {
+		inFlight atomic.Int64
+		notify   chan struct{}
+		timeout  atomic.Int64
+	}
+)
+
+const (
+	// DefaultDrainTimeout -- default maximum time Exit waits for the in-flight work
+	DefaultDrainTimeout = 3 * time.Second
+
+	drainProgressInterval = time.Second
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func (d *drain) init() {
+	d.notify = make(chan struct{}, 1)
+	d.timeout.Store(int64(DefaultDrainTimeout))
+}
+
+// SetDrainTimeout -- maximum time Exit waits for the in-flight work before calling finalizers, returns the previous value
+func (a *App) SetDrainTimeout(timeout time.Duration) (prev time.Duration) {
+	return time.Duration(a.drain.timeout.Swap(int64(timeout)))
+}
+
+// BeginWork -- register the in-flight unit of work (request, job etc.). Exit waits for the done call before finalizers.
+// ok is false if the application is stopped, the work should be rejected in that case
+func (a *App) BeginWork() (done func(), ok bool) {
+	// counted before the check: waitDrain either sees the work or the work sees the stopped application
+	a.drain.inFlight.Add(1)
+
+	var once sync.Once
+	done = func() {
+		once.Do(func() {
+			if a.drain.inFlight.Add(-1) == 0 {
+				select {
+				case a.drain.notify <- struct{}{}:
+				default:
+				}
+			}
+		})
+	}
+
+	if !a.Started() {
+		done()
+		return func() {}, false
+	}
+
+	return done, true
+}
+
+// InFlight -- number of the in-flight units of work
+func (a *App) InFlight() int64 {
+	return a.drain.inFlight.Load()
+}
+
+// waitDrain -- wait for all the in-flight work or the drain timeout
+func (a *App) waitDrain() {
+	if a.InFlight() == 0 {
+		return
+	}
+
+	timeout := time.Duration(a.drain.timeout.Load())
+	deadline := a.clock().After(timeout)
+
+	for {
+		n := a.InFlight()
+		if n <= 0 {
+			logMessage(LogLevelDebug, "All in-flight work completed")
+			return
+		}
+
+		logMessage(LogLevelInfo, "Waiting for %d in-flight unit(s) of work", n)
+
+		select {
+		case <-a.drain.notify:
+		case <-a.clock().After(drainProgressInterval):
+		case <-deadline:
+			logMessage(LogLevelWarning, "Drain timeout %s, %d in-flight unit(s) of work left", timeout, a.InFlight())
+			return
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetDrainTimeout --
+func SetDrainTimeout(timeout time.Duration) (prev time.Duration) {
+	return DefaultApp().SetDrainTimeout(timeout)
+}
+
+// BeginWork --
+func BeginWork() (done func(), ok bool) {
+	return DefaultApp().BeginWork()
+}
+
+// InFlight --
+func InFlight() int64 {
+	return DefaultApp().InFlight()
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
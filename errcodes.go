@@ -0,0 +1,301 @@
+package misc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/http"
+	"slices"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type (
+	// CanonicalCode -- transport independent error code (gRPC-style)
+	CanonicalCode int
+
+	// ProblemDetails -- RFC 7807 problem details, Extensions are added to the top level object
+	ProblemDetails struct {
+		Type       string
+		Title      string
+		Status     int
+		Detail     string
+		Instance   string
+		Extensions map[string]any
+	}
+
+	canonicalMapping struct {
+		name       string
+		httpStatus int
+		exitCode   int
+	}
+)
+
+// Canonical codes
+const (
+	CodeOK CanonicalCode = iota
+	CodeCanceled
+	CodeUnknown
+	CodeInvalidArgument
+	CodeDeadlineExceeded
+	CodeNotFound
+	CodeAlreadyExists
+	CodePermissionDenied
+	CodeResourceExhausted
+	CodeFailedPrecondition
+	CodeAborted
+	CodeOutOfRange
+	CodeUnimplemented
+	CodeInternal
+	CodeUnavailable
+	CodeDataLoss
+	CodeUnauthenticated
+)
+
+const (
+	// ProblemContentType -- content type of the problem details body
+	ProblemContentType = "application/problem+json"
+
+	// StatusClientClosedRequest -- non standard HTTP status used for the canceled requests
+	StatusClientClosedRequest = 499
+)
+
+var (
+	canonicalCodes = []canonicalMapping{
+		CodeOK:                 {"OK", http.StatusOK, 0},
+		CodeCanceled:           {"Canceled", StatusClientClosedRequest, ExStopped},
+		CodeUnknown:            {"Unknown", http.StatusInternalServerError, 70},
+		CodeInvalidArgument:    {"InvalidArgument", http.StatusBadRequest, 65},
+		CodeDeadlineExceeded:   {"DeadlineExceeded", http.StatusGatewayTimeout, 75},
+		CodeNotFound:           {"NotFound", http.StatusNotFound, 66},
+		CodeAlreadyExists:      {"AlreadyExists", http.StatusConflict, 73},
+		CodePermissionDenied:   {"PermissionDenied", http.StatusForbidden, 77},
+		CodeResourceExhausted:  {"ResourceExhausted", http.StatusTooManyRequests, 75},
+		CodeFailedPrecondition: {"FailedPrecondition", http.StatusBadRequest, 78},
+		CodeAborted:            {"Aborted", http.StatusConflict, 75},
+		CodeOutOfRange:         {"OutOfRange", http.StatusBadRequest, 65},
+		CodeUnimplemented:      {"Unimplemented", http.StatusNotImplemented, 70},
+		CodeInternal:           {"Internal", http.StatusInternalServerError, 70},
+		CodeUnavailable:        {"Unavailable", http.StatusServiceUnavailable, 69},
+		CodeDataLoss:           {"DataLoss", http.StatusInternalServerError, 74},
+		CodeUnauthenticated:    {"Unauthenticated", http.StatusUnauthorized, 77},
+	}
+
+	// HTTP statuses which can't be derived from canonicalCodes unambiguously
+	httpCanonical = map[int]CanonicalCode{
+		http.StatusBadRequest:                   CodeInvalidArgument,
+		http.StatusUnauthorized:                 CodeUnauthenticated,
+		http.StatusForbidden:                    CodePermissionDenied,
+		http.StatusNotFound:                     CodeNotFound,
+		http.StatusRequestTimeout:               CodeDeadlineExceeded,
+		http.StatusConflict:                     CodeAlreadyExists,
+		http.StatusPreconditionFailed:           CodeFailedPrecondition,
+		http.StatusRequestedRangeNotSatisfiable: CodeOutOfRange,
+		http.StatusTooManyRequests:              CodeResourceExhausted,
+		StatusClientClosedRequest:               CodeCanceled,
+		http.StatusInternalServerError:          CodeInternal,
+		http.StatusNotImplemented:               CodeUnimplemented,
+		http.StatusServiceUnavailable:           CodeUnavailable,
+		http.StatusGatewayTimeout:               CodeDeadlineExceeded,
+	}
+
+	// sysexits codes which can't be derived from canonicalCodes unambiguously
+	exitCanonical = map[int]CanonicalCode{
+		0:         CodeOK,
+		ExStopped: CodeCanceled,
+		64:        CodeInvalidArgument,    // EX_USAGE
+		65:        CodeInvalidArgument,    // EX_DATAERR
+		66:        CodeNotFound,           // EX_NOINPUT
+		67:        CodeNotFound,           // EX_NOUSER
+		68:        CodeNotFound,           // EX_NOHOST
+		69:        CodeUnavailable,        // EX_UNAVAILABLE
+		70:        CodeInternal,           // EX_SOFTWARE
+		71:        CodeInternal,           // EX_OSERR
+		72:        CodeInternal,           // EX_OSFILE
+		73:        CodeAlreadyExists,      // EX_CANTCREAT
+		74:        CodeDataLoss,           // EX_IOERR
+		75:        CodeUnavailable,        // EX_TEMPFAIL
+		76:        CodeInternal,           // EX_PROTOCOL
+		77:        CodePermissionDenied,   // EX_NOPERM
+		78:        CodeFailedPrecondition, // EX_CONFIG
+	}
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// String --
+func (c CanonicalCode) String() string {
+	if c < 0 || int(c) >= len(canonicalCodes) {
+		return fmt.Sprintf("?(%d)", c)
+	}
+
+	return canonicalCodes[c].name
+}
+
+// ParseCanonicalCode --
+func ParseCanonicalCode(s string) (CanonicalCode, error) {
+	i := slices.IndexFunc(canonicalCodes, func(m canonicalMapping) bool { return m.name == s })
+	if i < 0 {
+		return CodeUnknown, fmt.Errorf(`unknown canonical code "%s"`, s)
+	}
+
+	return CanonicalCode(i), nil
+}
+
+// MarshalText --
+func (c CanonicalCode) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText --
+func (c *CanonicalCode) UnmarshalText(text []byte) (err error) {
+	*c, err = ParseCanonicalCode(string(text))
+	return
+}
+
+// HTTPStatus -- HTTP status for the code
+func (c CanonicalCode) HTTPStatus() int {
+	if c < 0 || int(c) >= len(canonicalCodes) {
+		return http.StatusInternalServerError
+	}
+
+	return canonicalCodes[c].httpStatus
+}
+
+// ExitStatus -- process exit code for the code
+func (c CanonicalCode) ExitStatus() ExitStatus {
+	if c < 0 || int(c) >= len(canonicalCodes) {
+		return ExitStatus(ExProgrammerError)
+	}
+
+	return ExitStatus(canonicalCodes[c].exitCode)
+}
+
+// CanonicalFromHTTP -- canonical code for the HTTP status
+func CanonicalFromHTTP(status int) CanonicalCode {
+	if c, exists := httpCanonical[status]; exists {
+		return c
+	}
+
+	switch {
+	case status >= 200 && status < 400:
+		return CodeOK
+	case status >= 400 && status < 500:
+		return CodeFailedPrecondition
+	case status >= 500 && status < 600:
+		return CodeInternal
+	default:
+		return CodeUnknown
+	}
+}
+
+// CanonicalFromExitStatus -- canonical code for the process exit code
+func CanonicalFromExitStatus(code int) CanonicalCode {
+	if c, exists := exitCanonical[code]; exists {
+		return c
+	}
+
+	return CodeUnknown
+}
+
+// CanonicalCodeOf -- canonical code of the error: the code of *Error in the chain or the code derived from the standard errors
+func CanonicalCodeOf(err error) CanonicalCode {
+	if err == nil {
+		return CodeOK
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Canonical()
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return CodeCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return CodeDeadlineExceeded
+	case errors.Is(err, fs.ErrNotExist):
+		return CodeNotFound
+	case errors.Is(err, fs.ErrExist):
+		return CodeAlreadyExists
+	case errors.Is(err, fs.ErrPermission):
+		return CodePermissionDenied
+	default:
+		return CodeUnknown
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// NewProblemDetails -- problem details for the error. The canonical code name and the *Error attributes become the extensions
+func NewProblemDetails(err error) *ProblemDetails {
+	c := CanonicalCodeOf(err)
+	status := c.HTTPStatus()
+
+	p := &ProblemDetails{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Extensions: map[string]any{
+			"code": c.String(),
+		},
+	}
+
+	if err != nil {
+		p.Detail = err.Error()
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		for k, v := range e.attrs {
+			if _, exists := p.Extensions[k]; !exists {
+				p.Extensions[k] = v
+			}
+		}
+	}
+
+	return p
+}
+
+// MarshalJSON --
+func (p *ProblemDetails) MarshalJSON() ([]byte, error) {
+	v := make(map[string]any, len(p.Extensions)+5)
+	maps.Copy(v, p.Extensions)
+
+	v["type"] = p.Type
+	v["title"] = p.Title
+	v["status"] = p.Status
+	if p.Detail != "" {
+		v["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		v["instance"] = p.Instance
+	}
+
+	return json.Marshal(v)
+}
+
+// ProblemJSON -- RFC 7807 body for the error
+func ProblemJSON(err error) ([]byte, error) {
+	return json.Marshal(NewProblemDetails(err))
+}
+
+// WriteProblem -- write the problem details response for the error
+func WriteProblem(w http.ResponseWriter, err error) error {
+	p := NewProblemDetails(err)
+
+	body, e := json.Marshal(p)
+	if e != nil {
+		return e
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(p.Status)
+	_, e = w.Write(body)
+	return e
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
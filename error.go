@@ -1,13 +1,24 @@
 package misc
 
-import "fmt"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+)
 
 //----------------------------------------------------------------------------------------------------------------------------//
 
 // Error --
 type Error struct {
-	code int
-	msg  string
+	code  int
+	msg   string
+	cause error
+	attrs map[string]any
+	stack []CallStackFrame
+
+	canonical    CanonicalCode
+	canonicalSet bool
 }
 
 // SetCode --
@@ -22,7 +33,14 @@ func (me *Error) SetMessage(format string, options ...any) {
 
 // Error --
 func (me *Error) Error() string {
-	return me.msg
+	switch {
+	case me.cause == nil:
+		return me.msg
+	case me.msg == "":
+		return me.cause.Error()
+	default:
+		return me.msg + ": " + me.cause.Error()
+	}
 }
 
 // Code --
@@ -30,12 +48,136 @@ func (me *Error) Code() int {
 	return me.code
 }
 
+// SetCanonical -- set the canonical code explicitly
+func (me *Error) SetCanonical(c CanonicalCode) {
+	me.canonical = c
+	me.canonicalSet = true
+}
+
+// Canonical -- canonical code, if not set it is derived from the code as from the exit code
+func (me *Error) Canonical() CanonicalCode {
+	if me.canonicalSet {
+		return me.canonical
+	}
+
+	return CanonicalFromExitStatus(me.code)
+}
+
+// HTTPStatus -- HTTP status for the canonical code
+func (me *Error) HTTPStatus() int {
+	return me.Canonical().HTTPStatus()
+}
+
+// Message -- message without the cause
+func (me *Error) Message() string {
+	return me.msg
+}
+
+// Unwrap -- wrapped error
+func (me *Error) Unwrap() error {
+	return me.cause
+}
+
+// Is -- errors.Is(err, ErrorWithCode(code)) is true for any *Error with the same code in the chain
+func (me *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.code == me.code && (t.msg == "" || t.msg == me.msg)
+}
+
+// With -- add the key/value context
+func (me *Error) With(key string, value any) *Error {
+	if me.attrs == nil {
+		me.attrs = make(map[string]any, 4)
+	}
+	me.attrs[key] = value
+	return me
+}
+
+// Attrs -- key/value context
+func (me *Error) Attrs() map[string]any {
+	return maps.Clone(me.attrs)
+}
+
+// Stack -- call stack of the error creation
+func (me *Error) Stack() []CallStackFrame {
+	return me.stack
+}
+
+// MarshalJSON -- {"code":..., "canonical":..., "message":..., "attrs":{...}}, the call stack is added in the debug mode
+func (me *Error) MarshalJSON() ([]byte, error) {
+	v := struct {
+		Code      int              `json:"code"`
+		Canonical CanonicalCode    `json:"canonical"`
+		Message   string           `json:"message"`
+		Attrs     map[string]any   `json:"attrs,omitempty"`
+		Stack     []CallStackFrame `json:"stack,omitempty"`
+	}{
+		Code:      me.code,
+		Canonical: me.Canonical(),
+		Message:   me.Error(),
+		Attrs:     me.attrs,
+	}
+
+	if IsDebug() {
+		v.Stack = me.stack
+	}
+
+	return json.Marshal(v)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
 // MakeError --
 func MakeError(code int, format string, options ...any) *Error {
-	e := &Error{}
+	e := &Error{
+		stack: GetCallStack(1),
+	}
 	e.SetCode(code)
 	e.SetMessage(format, options...)
 	return e
 }
 
+// WrapError -- error with the code wrapping err, nil if err is nil
+func WrapError(err error, code int, format string, options ...any) *Error {
+	if err == nil {
+		return nil
+	}
+
+	e := &Error{
+		cause: err,
+		stack: GetCallStack(1),
+	}
+	e.SetCode(code)
+	if format != "" {
+		e.SetMessage(format, options...)
+	}
+	return e
+}
+
+// MakeCanonicalError -- error with the canonical code, the code is the corresponding exit code
+func MakeCanonicalError(c CanonicalCode, format string, options ...any) *Error {
+	e := &Error{
+		stack: GetCallStack(1),
+	}
+	e.SetCode(int(c.ExitStatus()))
+	e.SetCanonical(c)
+	e.SetMessage(format, options...)
+	return e
+}
+
+// ErrorWithCode -- error to compare with by errors.Is: errors.Is(err, ErrorWithCode(ExConfigErrors))
+func ErrorWithCode(code int) *Error {
+	return &Error{code: code}
+}
+
+// ErrorCode -- code of the first *Error in the chain
+func ErrorCode(err error) (code int, ok bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.code, true
+	}
+
+	return 0, false
+}
+
 //----------------------------------------------------------------------------------------------------------------------------//
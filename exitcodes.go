@@ -0,0 +1,210 @@
+package misc
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type (
+	// ExitStatus -- application exit code with the symbolic name and description from the registry
+	ExitStatus int
+
+	// ExitStatusInfo -- registered exit code
+	ExitStatusInfo struct {
+		Code        int      `json:"code"`
+		Names       []string `json:"names"`
+		Description string   `json:"description,omitempty"`
+		// sysexits.h name (EX_CONFIG etc.), "" if the code is out of sysexits
+		Category string `json:"category,omitempty"`
+	}
+)
+
+var (
+	sysexits = map[int][2]string{
+		0:  {"EX_OK", "successful termination"},
+		64: {"EX_USAGE", "command line usage error"},
+		65: {"EX_DATAERR", "data format error"},
+		66: {"EX_NOINPUT", "cannot open input"},
+		67: {"EX_NOUSER", "addressee unknown"},
+		68: {"EX_NOHOST", "host name unknown"},
+		69: {"EX_UNAVAILABLE", "service unavailable"},
+		70: {"EX_SOFTWARE", "internal software error"},
+		71: {"EX_OSERR", "system error"},
+		72: {"EX_OSFILE", "critical OS file missing"},
+		73: {"EX_CANTCREAT", "can't create (user) output file"},
+		74: {"EX_IOERR", "input/output error"},
+		75: {"EX_TEMPFAIL", "temporary failure"},
+		76: {"EX_PROTOCOL", "remote error in protocol"},
+		77: {"EX_NOPERM", "permission denied"},
+		78: {"EX_CONFIG", "configuration error"},
+	}
+
+	exitStatusesMutex      sync.RWMutex
+	exitStatuses           = make(map[int]*ExitStatusInfo, 32)
+	exitStatusNames        = make(map[string]int, 32)
+	exitStatusDescriptions = make(map[int][]string, 32) // joined into ExitStatusInfo.Description
+)
+
+func init() {
+	builtin := []struct {
+		code        int
+		name        string
+		description string
+	}{
+		{0, "ExOK", "successful termination"},
+		{ExStopped, "ExStopped", "application stopped"},
+		{ExVersion, "ExVersion", "version requested"},
+		{ExMissingConfigFile, "ExMissingConfigFile", "missing config file"},
+		{ExIncorrectConfigFile, "ExIncorrectConfigFile", "incorrect config file"},
+		{ExConfigIncorrect, "ExConfigIncorrect", "incorrect config"},
+		{ExConfigErrors, "ExConfigErrors", "config errors"},
+		{ExCreateListenerError, "ExCreateListenerError", "listener creation error"},
+		{ExStartListenerError, "ExStartListenerError", "listener start error"},
+		{ExServiceInitializationError, "ExServiceInitializationError", "service initialization error"},
+		{ExServiceError, "ExServiceError", "service error"},
+		{ExAccessDenied, "ExAccessDenied", "access denied"},
+		{ExPanic, "ExPanic", "panic"},
+		{ExProgrammerError, "ExProgrammerError", "programmer error"},
+		{ExPidFileError, "ExPidFileError", "PID file error"},
+		{ExAlreadyRunning, "ExAlreadyRunning", "application is already running"},
+	}
+
+	for _, df := range builtin {
+		RegisterExitStatus(df.code, df.name, df.description)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// RegisterExitStatus -- register the application exit code. Several names may share the same code (the description is joined),
+// but the name can't be reused for another code
+func RegisterExitStatus(code int, name string, description string) (ExitStatus, error) {
+	if name == "" {
+		return ExitStatus(code), fmt.Errorf("exit code %d: empty name", code)
+	}
+
+	exitStatusesMutex.Lock()
+	defer exitStatusesMutex.Unlock()
+
+	if c, exists := exitStatusNames[name]; exists {
+		if c != code {
+			return ExitStatus(code), fmt.Errorf(`exit code name "%s" is already used for %d`, name, c)
+		}
+		return ExitStatus(code), nil
+	}
+
+	info, exists := exitStatuses[code]
+	if !exists {
+		info = &ExitStatusInfo{
+			Code: code,
+		}
+		if sx, exists := sysexits[code]; exists {
+			info.Category = sx[0]
+		}
+		exitStatuses[code] = info
+	}
+
+	info.Names = append(info.Names, name)
+
+	if description != "" && !slices.Contains(exitStatusDescriptions[code], description) {
+		exitStatusDescriptions[code] = append(exitStatusDescriptions[code], description)
+		info.Description = strings.Join(exitStatusDescriptions[code], " / ")
+	}
+
+	exitStatusNames[name] = code
+
+	return ExitStatus(code), nil
+}
+
+// ExitStatusByName -- registered code by the name
+func ExitStatusByName(name string) (status ExitStatus, ok bool) {
+	exitStatusesMutex.RLock()
+	defer exitStatusesMutex.RUnlock()
+
+	code, ok := exitStatusNames[name]
+	return ExitStatus(code), ok
+}
+
+// ExitStatuses -- all registered codes
+func ExitStatuses() []ExitStatusInfo {
+	exitStatusesMutex.RLock()
+	defer exitStatusesMutex.RUnlock()
+
+	list := make([]ExitStatusInfo, 0, len(exitStatuses))
+	for _, info := range exitStatuses {
+		i := *info
+		i.Names = slices.Clone(info.Names)
+		list = append(list, i)
+	}
+
+	slices.SortFunc(list, func(a, b ExitStatusInfo) int { return a.Code - b.Code })
+	return list
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Info -- registry information, for the unregistered codes only the sysexits category and description are filled
+func (s ExitStatus) Info() ExitStatusInfo {
+	exitStatusesMutex.RLock()
+	info, exists := exitStatuses[int(s)]
+	exitStatusesMutex.RUnlock()
+
+	if exists {
+		i := *info
+		i.Names = slices.Clone(info.Names)
+		return i
+	}
+
+	i := ExitStatusInfo{
+		Code: int(s),
+	}
+	if sx, exists := sysexits[int(s)]; exists {
+		i.Category = sx[0]
+		i.Description = sx[1]
+	}
+	return i
+}
+
+// Name -- registered names joined by "|", "" if not registered
+func (s ExitStatus) Name() string {
+	return strings.Join(s.Info().Names, "|")
+}
+
+// String -- code with the symbolic names and the sysexits category: "78 (ExIncorrectConfigFile|ExConfigIncorrect|ExConfigErrors, EX_CONFIG)"
+func (s ExitStatus) String() string {
+	return s.format(s.Info().Names)
+}
+
+// NamedString -- code with the given registered name only: "78 (ExConfigErrors, EX_CONFIG)". String if the name isn't registered for the code
+func (s ExitStatus) NamedString(name string) string {
+	if code, ok := ExitStatusByName(name); !ok || code != s {
+		return s.String()
+	}
+
+	return s.format([]string{name})
+}
+
+func (s ExitStatus) format(names []string) string {
+	info := s.Info()
+
+	parts := make([]string, 0, 2)
+	if len(names) > 0 {
+		parts = append(parts, strings.Join(names, "|"))
+	}
+	if info.Category != "" {
+		parts = append(parts, info.Category)
+	}
+
+	if len(parts) == 0 {
+		return strconv.Itoa(int(s))
+	}
+
+	return strconv.Itoa(int(s)) + " (" + strings.Join(parts, ", ") + ")"
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
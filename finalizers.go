@@ -0,0 +1,295 @@
+package misc
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type (
+	// FinalizerFunc --
+	FinalizerFunc func(code int, param any)
+
+	// FinalizerOptions -- options of the finalizer
+	FinalizerOptions struct {
+		// Names of the finalizers this one depends on. The finalizer is called before them,
+		// so resources it uses are still alive
+		DependsOn []string
+		// Own finalizer timeout, 0 -- without timeout
+		Timeout time.Duration
+	}
+
+	// FinalizerResult -- result of the finalizer call
+	FinalizerResult struct {
+		Name     string
+		Duration time.Duration
+		TimedOut bool
+		Panicked bool
+		Panic    any
+		Stack    []CallStackFrame
+	}
+
+	exitElement struct {
+		name  string
+		f     FinalizerFunc
+		param any
+		opts  FinalizerOptions
+		// added by AddFinalizer, called in the reverse order of the registration
+		serial bool
+	}
+)
+
+// AddFinalizer -- add finalizer. Finalizers added by this function are called serially in the reverse order of the registration
+func (a *App) AddFinalizer(name string, f FinalizerFunc, param any) {
+	a.addFinalizer(exitElement{name: name, f: f, param: param, serial: true})
+}
+
+// AddFinalizerEx -- add finalizer with options. Independent finalizers are called in parallel
+func (a *App) AddFinalizerEx(name string, f FinalizerFunc, param any, opts FinalizerOptions) {
+	opts.DependsOn = slices.Clone(opts.DependsOn)
+	a.addFinalizer(exitElement{name: name, f: f, param: param, opts: opts})
+}
+
+func (a *App) addFinalizer(e exitElement) {
+	a.finalizersMutex.Lock()
+	defer a.finalizersMutex.Unlock()
+
+	a.delFinalizer(e.name)
+	a.finalizers = append(a.finalizers, e)
+}
+
+// DelFinalizer --
+func (a *App) DelFinalizer(name string) {
+	a.finalizersMutex.Lock()
+	defer a.finalizersMutex.Unlock()
+
+	a.delFinalizer(name)
+}
+
+func (a *App) delFinalizer(name string) {
+	chain := make([]exitElement, 0)
+	for i := 0; i < len(a.finalizers); i++ {
+		if a.finalizers[i].name != name {
+			chain = append(chain, a.finalizers[i])
+		}
+	}
+	a.finalizers = chain
+}
+
+// FinalizerNames -- names of the registered finalizers in the registration order
+func (a *App) FinalizerNames() []string {
+	a.finalizersMutex.RLock()
+	defer a.finalizersMutex.RUnlock()
+
+	names := make([]string, len(a.finalizers))
+	for i, e := range a.finalizers {
+		names[i] = e.name
+	}
+
+	return names
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// AddFinalizer -- add finalizer. Finalizers added by this function are called serially in the reverse order of the registration
+func AddFinalizer(name string, f FinalizerFunc, param any) {
+	DefaultApp().AddFinalizer(name, f, param)
+}
+
+// AddFinalizerEx -- add finalizer with options. Independent finalizers are called in parallel
+func AddFinalizerEx(name string, f FinalizerFunc, param any, opts FinalizerOptions) {
+	DefaultApp().AddFinalizerEx(name, f, param, opts)
+}
+
+// DelFinalizer --
+func DelFinalizer(name string) {
+	DefaultApp().DelFinalizer(name)
+}
+
+// FinalizerNames --
+func FinalizerNames() []string {
+	return DefaultApp().FinalizerNames()
+}
+
+var AddExitFunc = AddFinalizer
+var DelExitFunc = DelFinalizer
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// finalizersGraph -- for each finalizer the list of indexes of the finalizers that must be completed before it
+func finalizersGraph(list []exitElement) (waitFor [][]int) {
+	n := len(list)
+
+	index := make(map[string]int, n)
+	for i, e := range list {
+		index[e.name] = i
+	}
+
+	waitFor = make([][]int, n)
+	lastSerial := -1
+
+	for i, e := range list {
+		if e.serial {
+			if lastSerial >= 0 {
+				waitFor[lastSerial] = append(waitFor[lastSerial], i)
+			}
+			lastSerial = i
+		}
+
+		for _, dep := range e.opts.DependsOn {
+			j, exists := index[dep]
+			if !exists {
+				logMessage(LogLevelWarning, `Finalizer "%s" depends on unknown finalizer "%s"`, e.name, dep)
+				continue
+			}
+			if j == i {
+				continue
+			}
+			waitFor[j] = append(waitFor[j], i)
+		}
+	}
+
+	// Looking for cycles (Kahn's algorithm)
+
+	inDegree := make([]int, n)
+	next := make([][]int, n)
+	for i, w := range waitFor {
+		inDegree[i] = len(w)
+		for _, j := range w {
+			next[j] = append(next[j], i)
+		}
+	}
+
+	queue := make([]int, 0, n)
+	for i, d := range inDegree {
+		if d == 0 {
+			queue = append(queue, i)
+		}
+	}
+
+	processed := make([]bool, n)
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		processed[i] = true
+
+		for _, j := range next[i] {
+			inDegree[j]--
+			if inDegree[j] == 0 {
+				queue = append(queue, j)
+			}
+		}
+	}
+
+	// The rest are in cycles or depend on them. Replace links between them by the reverse order of the registration
+
+	prev := -1
+	for i := n - 1; i >= 0; i-- {
+		if processed[i] {
+			continue
+		}
+
+		logMessage(LogLevelWarning, `Finalizer "%s" is in the dependency cycle, the registration order is used`, list[i].name)
+
+		waitFor[i] = slices.DeleteFunc(waitFor[i], func(j int) bool { return !processed[j] })
+		if prev >= 0 {
+			waitFor[i] = append(waitFor[i], prev)
+		}
+		prev = i
+	}
+
+	return
+}
+
+// callFinalizers -- call all finalizers according to their dependencies
+func (a *App) callFinalizers(code int) []FinalizerResult {
+	a.finalizersMutex.RLock()
+	list := slices.Clone(a.finalizers)
+	a.finalizersMutex.RUnlock()
+
+	waitFor := finalizersGraph(list)
+
+	results := make([]FinalizerResult, len(list))
+	done := make([]chan struct{}, len(list))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	var wg sync.WaitGroup
+
+	for i, e := range list {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(done[i])
+
+			for _, j := range waitFor[i] {
+				<-done[j]
+			}
+
+			results[i] = a.callFinalizer(code, &e)
+		}()
+	}
+
+	wg.Wait()
+	return results
+}
+
+func (a *App) callFinalizer(code int, e *exitElement) (result FinalizerResult) {
+	result.Name = e.name
+
+	logMessage(LogLevelDebug, `Call finalizer "%s"`, e.name)
+
+	t0 := a.clock().Now()
+	finished := make(chan FinalizerResult, 1)
+
+	go func() {
+		r := FinalizerResult{}
+
+		defer func() {
+			r.Panic = recover()
+			if r.Panicked {
+				r.Stack = GetCallStack(0)
+				logMessage(LogLevelError, "Finalizer \"%s\" panicked after %s: %v\n%s", e.name, a.clock().Now().Sub(t0), r.Panic, FormatCallStack(r.Stack))
+			}
+			finished <- r
+		}()
+
+		r.Panicked = true
+		e.f(code, e.param)
+		r.Panicked = false
+	}()
+
+	var timeout <-chan time.Time
+	if e.opts.Timeout > 0 {
+		timeout = a.clock().After(e.opts.Timeout)
+	}
+
+	select {
+	case r := <-finished:
+		result.Panicked, result.Panic, result.Stack = r.Panicked, r.Panic, r.Stack
+	case <-timeout:
+		result.TimedOut = true
+	}
+
+	result.Duration = a.clock().Now().Sub(t0)
+	return
+}
+
+// logFinalizersReport -- log timed out finalizers, returns true if any of them panicked (panics are already logged with the stack by callFinalizer)
+func logFinalizersReport(results []FinalizerResult) (panicked bool) {
+	for _, r := range results {
+		switch {
+		case r.TimedOut:
+			logMessage(LogLevelError, `Finalizer "%s" timed out after %s`, r.Name, r.Duration)
+		case r.Panicked:
+			panicked = true
+		}
+	}
+
+	return
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,329 @@
+package misc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type (
+	// LogLevel -- log level, the less the value the more important the message
+	LogLevel int
+
+	// StructuredLogger -- logger with key/value attributes
+	StructuredLogger interface {
+		Enabled(facility string, level LogLevel) bool
+		Log(facility string, level LogLevel, message string, args ...any)
+	}
+
+	slogLogger struct {
+		l *slog.Logger
+	}
+
+	// SlogHandler -- slog.Handler writing records through the Logger
+	SlogHandler struct {
+		facility string
+		level    slog.Leveler
+		prefix   string
+		attrs    string
+	}
+)
+
+// Log levels
+const (
+	LogLevelEmergency LogLevel = iota
+	LogLevelAlert
+	LogLevelCritical
+	LogLevelError
+	LogLevelWarning
+	LogLevelNotice
+	LogLevelInfo
+	LogLevelDebug
+	LogLevelTime
+	LogLevelTrace
+)
+
+var (
+	logLevelNames = []string{"EM", "AL", "CR", "ER", "WA", "NO", "IN", "DE", "TM", "TR"}
+
+	structuredLoggerMutex sync.RWMutex
+	structuredLogger      StructuredLogger
+
+	loggerPtr atomic.Pointer[loggerFunc] // set by SetLogger, overrides Logger
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// String -- two-letter name of the level used by the Logger
+func (l LogLevel) String() string {
+	if l < 0 || int(l) >= len(logLevelNames) {
+		return fmt.Sprintf("?(%d)", l)
+	}
+
+	return logLevelNames[l]
+}
+
+// ParseLogLevel -- level by the two-letter name
+func ParseLogLevel(name string) (level LogLevel, ok bool) {
+	for i, n := range logLevelNames {
+		if strings.EqualFold(n, name) {
+			return LogLevel(i), true
+		}
+	}
+
+	return LogLevelInfo, false
+}
+
+// SlogLevel -- corresponding slog level
+func (l LogLevel) SlogLevel() slog.Level {
+	switch l {
+	case LogLevelEmergency:
+		return slog.LevelError + 12
+	case LogLevelAlert:
+		return slog.LevelError + 8
+	case LogLevelCritical:
+		return slog.LevelError + 4
+	case LogLevelError:
+		return slog.LevelError
+	case LogLevelWarning:
+		return slog.LevelWarn
+	case LogLevelNotice:
+		return slog.LevelInfo + 2
+	case LogLevelInfo:
+		return slog.LevelInfo
+	case LogLevelDebug, LogLevelTime:
+		return slog.LevelDebug
+	default:
+		return slog.LevelDebug - 4
+	}
+}
+
+// LogLevelFromSlog -- level corresponding to the slog level
+func LogLevelFromSlog(level slog.Level) LogLevel {
+	switch {
+	case level >= slog.LevelError+12:
+		return LogLevelEmergency
+	case level >= slog.LevelError+8:
+		return LogLevelAlert
+	case level >= slog.LevelError+4:
+		return LogLevelCritical
+	case level >= slog.LevelError:
+		return LogLevelError
+	case level >= slog.LevelWarn:
+		return LogLevelWarning
+	case level >= slog.LevelInfo+2:
+		return LogLevelNotice
+	case level >= slog.LevelInfo:
+		return LogLevelInfo
+	case level >= slog.LevelDebug:
+		return LogLevelDebug
+	default:
+		return LogLevelTrace
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetLogger -- replace the Logger safely at any time, nil -- use the Logger variable again
+func SetLogger(f loggerFunc) (prev loggerFunc) {
+	prev = GetLogger()
+
+	if f == nil {
+		loggerPtr.Store(nil)
+	} else {
+		loggerPtr.Store(&f)
+	}
+
+	return
+}
+
+// GetLogger -- current messages writer: set by SetLogger or the Logger variable
+func GetLogger() loggerFunc {
+	if f := loggerPtr.Load(); f != nil {
+		return *f
+	}
+
+	return Logger
+}
+
+// logMessage -- package internal messages, filtered by the default verbosity
+func logMessage(level LogLevel, message string, params ...any) {
+	if IsLogEnabled("", level) {
+		GetLogger()("", level.String(), message, params...)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetStructuredLogger -- set the logger used by LogAttrs, nil -- LogAttrs writes through the Logger
+func SetStructuredLogger(l StructuredLogger) (prev StructuredLogger) {
+	structuredLoggerMutex.Lock()
+	defer structuredLoggerMutex.Unlock()
+
+	prev, structuredLogger = structuredLogger, l
+	return
+}
+
+// LogAttrs -- write the message with key/value attributes (like slog: "key1", value1, "key2", value2, ...)
+// if it is enabled by the facility verbosity
+func LogAttrs(facility string, level LogLevel, message string, args ...any) {
+	if !IsLogEnabled(facility, level) {
+		return
+	}
+
+	structuredLoggerMutex.RLock()
+	l := structuredLogger
+	structuredLoggerMutex.RUnlock()
+
+	if l != nil {
+		if l.Enabled(facility, level) {
+			l.Log(facility, level, message, args...)
+		}
+		return
+	}
+
+	r := slog.NewRecord(NowUTC(), level.SlogLevel(), message, 0)
+	r.Add(args...)
+
+	var b strings.Builder
+	b.WriteString(message)
+	r.Attrs(func(a slog.Attr) bool {
+		writeSlogAttr(&b, "", a)
+		return true
+	})
+
+	GetLogger()(facility, level.String(), "%s", b.String())
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// NewSlogLogger -- StructuredLogger writing to the slog.Logger, the facility is added as the "facility" attribute
+func NewSlogLogger(l *slog.Logger) StructuredLogger {
+	return &slogLogger{l: l}
+}
+
+// Enabled --
+func (s *slogLogger) Enabled(facility string, level LogLevel) bool {
+	return s.l.Enabled(context.Background(), level.SlogLevel())
+}
+
+// Log --
+func (s *slogLogger) Log(facility string, level LogLevel, message string, args ...any) {
+	if facility != "" {
+		args = append([]any{slog.String("facility", facility)}, args...)
+	}
+
+	s.l.Log(context.Background(), level.SlogLevel(), message, args...)
+}
+
+// LoggerFromSlog -- function suitable for the Logger writing to the slog.Logger
+func LoggerFromSlog(l *slog.Logger) func(facility string, level string, message string, params ...any) {
+	s := NewSlogLogger(l)
+
+	return func(facility string, level string, message string, params ...any) {
+		lvl, _ := ParseLogLevel(level)
+		if s.Enabled(facility, lvl) {
+			s.Log(facility, lvl, fmt.Sprintf(message, params...))
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// NewSlogHandler -- slog.Handler writing records through the Logger with the given facility.
+// level is the minimal level, nil -- slog.LevelInfo
+func NewSlogHandler(facility string, level slog.Leveler) *SlogHandler {
+	if level == nil {
+		level = slog.LevelInfo
+	}
+
+	return &SlogHandler{
+		facility: facility,
+		level:    level,
+	}
+}
+
+// Enabled -- the level is not less than the handler level and is enabled by the facility verbosity
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level() && IsLogEnabled(h.facility, LogLevelFromSlog(level))
+}
+
+// Handle --
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	if !h.Enabled(ctx, r.Level) {
+		return nil
+	}
+
+	var b strings.Builder
+	b.WriteString(r.Message)
+	b.WriteString(h.attrs)
+
+	r.Attrs(func(a slog.Attr) bool {
+		writeSlogAttr(&b, h.prefix, a)
+		return true
+	})
+
+	GetLogger()(h.facility, LogLevelFromSlog(r.Level).String(), "%s", b.String())
+	return nil
+}
+
+// WithAttrs --
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+
+	var b strings.Builder
+	b.WriteString(h.attrs)
+	for _, a := range attrs {
+		writeSlogAttr(&b, h.prefix, a)
+	}
+	h2.attrs = b.String()
+
+	return &h2
+}
+
+// WithGroup --
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+func writeSlogAttr(b *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			writeSlogAttr(b, prefix, ga)
+		}
+		return
+	}
+
+	b.WriteString(" ")
+	b.WriteString(prefix)
+	b.WriteString(a.Key)
+	b.WriteString("=")
+
+	v := a.Value.String()
+	if strings.ContainsAny(v, " \t\"=") {
+		v = fmt.Sprintf("%q", v)
+	}
+	b.WriteString(v)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -8,12 +8,12 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
-	"net"
+	"iter"
 	"os"
 	"path/filepath"
 	"reflect"
 	"regexp"
-	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -26,7 +26,8 @@ import (
 
 //----------------------------------------------------------------------------------------------------------------------------//
 
-// Appliction exit codes
+// Appliction exit codes. Some of them share the same value, they are registered with the names
+// and sysexits categories, see ExitStatus
 const (
 	// ExPanic --
 	ExPanic = 70
@@ -54,6 +55,10 @@ const (
 	ExAccessDenied = 77
 	// ExProgrammerError --
 	ExProgrammerError = 70
+	// ExPidFileError --
+	ExPidFileError = 73
+	// ExAlreadyRunning --
+	ExAlreadyRunning = 75
 )
 
 //----------------------------------------------------------------------------------------------------------------------------//
@@ -86,31 +91,11 @@ var (
 	appName     string
 	appWorkDir  string
 
-	appStarted     = int32(1)
-	exitLaunched   = int32(0)
-	appInitialized = int32(0)
-
-	exitCode = 0
-
-	exitTrigger = make(chan struct{})
-
-	finalizers      = make([]exitElement, 0)
-	finalizersMutex sync.RWMutex
-
-	// Logger --
+	// Logger -- messages writer. Assign it only at the initialization, before any signal can arrive, later use SetLogger
 	Logger loggerFunc
 )
 
 type (
-	// FinalizerFunc --
-	FinalizerFunc func(code int, param any)
-
-	exitElement struct {
-		name  string
-		f     FinalizerFunc
-		param any
-	}
-
 	loggerFunc func(facility string, level string, message string, params ...any)
 )
 
@@ -120,110 +105,6 @@ type (
 
 //----------------------------------------------------------------------------------------------------------------------------//
 
-var (
-	terminationTimeout = 5 * time.Second
-	killingTimeout     = 5 * time.Second
-)
-
-func SetExitTimeouts(newTerminationTimeout time.Duration, newKillingTimeout time.Duration) (prevTerminationTimeout time.Duration, prevKillingTimeout time.Duration) {
-	prevTerminationTimeout, prevKillingTimeout = terminationTimeout, killingTimeout
-
-	if newTerminationTimeout > 0 {
-		terminationTimeout = newTerminationTimeout
-	}
-
-	if newKillingTimeout > 0 {
-		killingTimeout = newKillingTimeout
-	}
-
-	return
-}
-
-func killer() {
-	time.Sleep(terminationTimeout)
-	Logger("", "NO", "Application shutdown timeout. Force termination.")
-	go Exit()
-
-	time.Sleep(killingTimeout)
-	Logger("", "NO", "Application termination timeout. Force killing.")
-	os.Exit(exitCode)
-}
-
-// StopApp -- set exit code and raise application stop
-func StopApp(code int) {
-	if atomic.AddInt32(&appStarted, -1) == 0 {
-		Logger("", "DE", "Set application exit code %d", code)
-
-		exitCode = code
-		close(exitTrigger)
-
-		go killer()
-	}
-}
-
-// WaitingForStop --
-func WaitingForStop() {
-	<-exitTrigger
-}
-
-// ApplicationStopped --
-func ApplicationStopped() <-chan struct{} {
-	return exitTrigger
-}
-
-// Exit -- exit application
-func Exit() {
-	if atomic.AddInt32(&exitLaunched, 1) == 1 {
-		if AppStarted() {
-			StopApp(0)
-		}
-
-		Logger("", "IN", "Try to finish application with code %d", exitCode)
-
-		time.Sleep(1000 * time.Millisecond)
-
-		finalizersMutex.RLock()
-		defer finalizersMutex.RUnlock()
-
-		for i := len(finalizers) - 1; i >= 0; i-- {
-			Logger("", "DE", "Call finalizer \"%s\"", finalizers[i].name)
-			finalizers[i].f(exitCode, finalizers[i].param)
-		}
-
-		Logger("", "IN", "Application finished with code %d", exitCode)
-		os.Exit(exitCode)
-	}
-}
-
-// AddFinalizer --
-func AddFinalizer(name string, f FinalizerFunc, param any) {
-	DelFinalizer(name)
-
-	finalizersMutex.Lock()
-	defer finalizersMutex.Unlock()
-
-	finalizers = append(finalizers, exitElement{name: name, f: f, param: param})
-}
-
-// DelFinalizer --
-func DelFinalizer(name string) {
-	finalizersMutex.Lock()
-	defer finalizersMutex.Unlock()
-
-	chain := make([]exitElement, 0)
-	for i := 0; i < len(finalizers); i++ {
-		if finalizers[i].name != name {
-			chain = append(chain, finalizers[i])
-		}
-	}
-	finalizers = chain
-}
-
-var AddExitFunc = AddFinalizer
-var DelExitFunc = DelFinalizer
-
-//----------------------------------------------------------------------------------------------------------------------------//
-
 // SimpleLogger --
 func simpleLogger(facility string, level string, message string, params ...any) {
 	fmt.Printf(level+": "+message+EOS, params...)
@@ -256,28 +137,18 @@ func init() {
 
 //----------------------------------------------------------------------------------------------------------------------------//
 
-// AbsPathEx --
+// AbsPathEx -- absolute path. The name may start with:
+//
+//	@ -- application work directory
+//	$ -- current directory
+//	^ -- base
+//	~ -- user home directory
+//	{root} -- registered path root, see RegisterPathRoot
+//
+// ${VAR} is expanded anywhere in the name ("$" without "{" is not), so the name starting with "${" is not the current directory one.
+// Other relative names are relative to the application executable directory
 func AbsPathEx(name string, base string) (string, error) {
-	prefix := ""
-	if name != "" {
-		prefix = name[0:1]
-	}
-
-	switch prefix {
-	case "@":
-		name = appWorkDir + "/" + name[1:]
-	case "$":
-		d, _ := os.Getwd()
-		name = d + "/" + name[1:]
-	case "^":
-		name = base + "/" + name[1:]
-	default:
-		if !filepath.IsAbs(name) {
-			name = AppExecPath() + "/" + name
-		}
-	}
-
-	return filepath.Abs(name)
+	return absPath(name, base, 0, true)
 }
 
 // AbsPath --
@@ -288,16 +159,25 @@ func AbsPath(name string) (string, error) {
 //----------------------------------------------------------------------------------------------------------------------------//
 
 var (
-	forcedDebugMode = false
+	forcedDebugMode atomic.Bool
 )
 
+// SetDebugMode -- force the debug mode, safe to call at runtime
 func SetDebugMode(mode bool) {
-	forcedDebugMode = mode
+	if forcedDebugMode.Swap(mode) == mode {
+		return
+	}
+
+	state := "off"
+	if mode {
+		state = "on"
+	}
+	logMessage(LogLevelNotice, "Debug mode is %s", state)
 }
 
 // IsDebug --
 func IsDebug() bool {
-	return forcedDebugMode || strings.HasPrefix(appExecName, "__debug") // simple workaround for the VS Code
+	return forcedDebugMode.Load() || strings.HasPrefix(appExecName, "__debug") // simple workaround for the VS Code
 }
 
 //----------------------------------------------------------------------------------------------------------------------------//
@@ -359,97 +239,6 @@ func AppWorkDir() string {
 	return appWorkDir
 }
 
-// AppStarted -- is application started?
-func AppStarted() bool {
-	return atomic.LoadInt32(&appStarted) > 0
-}
-
-func Initialized() {
-	atomic.StoreInt32(&appInitialized, 1)
-}
-
-func AppInitialized() bool {
-	return atomic.LoadInt32(&appInitialized) > 0
-}
-
-// ExitCode -- get current exit code
-func ExitCode() int {
-	return exitCode
-}
-
-//----------------------------------------------------------------------------------------------------------------------------//
-
-// GetFuncName -- name of the function from call stack
-func GetFuncName(shift int, shortName bool) string {
-	ret := ""
-
-	stack := GetCallStack(shift + 1)
-	n := len(stack)
-
-	if shortName {
-		n = 1
-	}
-
-	for i := n - 1; i >= 0; i-- {
-		df := stack[i]
-
-		if i != n-1 {
-			ret += "->"
-		}
-		ret += filepath.Base(df.FuncName)
-	}
-
-	return ret
-}
-
-// CallStackFrame -- call stack element
-type CallStackFrame struct {
-	FuncName string
-	FileName string
-	Line     int
-}
-
-// GetCallStack -- get call stack
-func GetCallStack(shift int) []CallStackFrame {
-	var ret []CallStackFrame
-
-	pc := make([]uintptr, 500)
-	n := runtime.Callers(2+shift, pc)
-	for i := 0; i < n; i++ {
-		frame := CallStackFrame{}
-		df := pc[i]
-
-		fn := runtime.FuncForPC(df)
-		if fn != nil {
-			frame.FuncName = fn.Name()
-		} else {
-			frame.FuncName = "?"
-		}
-
-		frame.FileName, frame.Line = fn.FileLine(df)
-
-		ret = append(ret, frame)
-	}
-
-	return ret
-}
-
-//----------------------------------------------------------------------------------------------------------------------------//
-
-// Sleep --
-func Sleep(duration time.Duration) bool {
-	if !AppStarted() {
-		return false
-	}
-
-	select {
-	case <-exitTrigger:
-		return false
-	case <-time.After(duration):
-		return true
-	}
-}
-
 //----------------------------------------------------------------------------------------------------------------------------//
 
 // TrimStringAsFloat --
@@ -469,71 +258,62 @@ func TrimStringAsFloat(s string) string {
 
 //----------------------------------------------------------------------------------------------------------------------------//
 
-// GetMyIPs --
-func GetMyIPs() (map[string]bool, error) {
-	ifaces, err := net.Interfaces()
-	if err != nil {
-		return nil, err
+type (
+	// Messages -- collected messages, for example config validation errors and warnings
+	Messages struct {
+		sync.RWMutex
+		entries []messageEntry
+		freed   atomic.Bool
 	}
 
-	list := make(map[string]bool)
-
-	for _, i := range ifaces {
-		addrs, err := i.Addrs()
-		if err != nil {
-			return nil, err
-		}
-
-		for _, addr := range addrs {
-			ip := ""
-			switch v := addr.(type) {
-			case *net.IPNet:
-				ip = v.IP.String()
-			case *net.IPAddr:
-				ip = v.IP.String()
-			}
+	// MessageSeverity --
+	MessageSeverity int
 
-			if ip != "" {
-				list[ip] = true
-			}
-		}
+	// MultiError -- error combining several errors, supports errors.Is/As over all of them as errors.Join does
+	MultiError struct {
+		errs []error
+		sep  string
 	}
 
-	return list, nil
-}
-
-// IsMyIP --
-func IsMyIP(ip string) (bool, error) {
-	list, err := GetMyIPs()
-	if err != nil {
-		return false, err
+	messageEntry struct {
+		severity MessageSeverity
+		err      error
 	}
+)
 
-	_, exists := list[ip]
-	return exists, nil
-}
-
-//----------------------------------------------------------------------------------------------------------------------------//
-
-// Messages --
-type Messages struct {
-	sync.RWMutex
-	s []string
-}
+// Message severities
+const (
+	// MessageSeverityError -- the message makes Messages.Error not nil
+	MessageSeverityError MessageSeverity = iota
+	// MessageSeverityWarning -- the message is reported by Messages.Warning only
+	MessageSeverityWarning
+)
 
 var (
 	messagesPool = sync.Pool{
 		New: func() any {
 			return &Messages{
-				s: make([]string, 0, 16),
+				entries: make([]messageEntry, 0, 16),
 			}
 		},
 	}
 )
 
+// Free -- return to the pool, m must not be used after that.
+// In the debug mode the object is not returned to the pool and any following use panics
 func (m *Messages) Free() {
-	clear(m.s)
-	m.s = m.s[0:0]
+	if IsDebug() {
+		if m.freed.Swap(true) {
+			panic("misc.Messages: double Free")
+		}
+		return
+	}
+
+	m.Lock()
+	clear(m.entries)
+	m.entries = m.entries[0:0]
+	m.Unlock()
+
 	messagesPool.Put(m)
 }
 
@@ -542,68 +322,191 @@ func NewMessages() *Messages {
 	return messagesPool.Get().(*Messages)
 }
 
-// Len --
+func (m *Messages) check() {
+	if m.freed.Load() {
+		panic("misc.Messages: used after Free")
+	}
+}
+
+func (m *Messages) snapshot() []messageEntry {
+	m.check()
+
+	m.RLock()
+	defer m.RUnlock()
+
+	return slices.Clone(m.entries)
+}
+
+// Len -- number of the error messages
 func (m *Messages) Len() int {
+	return m.Count(MessageSeverityError)
+}
+
+// Count -- number of the messages with the severity
+func (m *Messages) Count(severity MessageSeverity) int {
+	m.check()
+
 	m.RLock()
 	defer m.RUnlock()
 
-	return len(m.s)
+	n := 0
+	for _, e := range m.entries {
+		if e.severity == severity {
+			n++
+		}
+	}
+	return n
 }
 
-// Add --
+// Add -- add the error message
 func (m *Messages) Add(msg string, params ...any) {
 	if msg != "" {
-		m.Lock()
-		defer m.Unlock()
+		m.AddErrorEx(MessageSeverityError, errors.New(fmt.Sprintf(msg, params...)))
+	}
+}
 
-		m.s = append(m.s, fmt.Sprintf(msg, params...))
+// AddWarning -- add the warning message
+func (m *Messages) AddWarning(msg string, params ...any) {
+	if msg != "" {
+		m.AddErrorEx(MessageSeverityWarning, errors.New(fmt.Sprintf(msg, params...)))
 	}
 }
 
-// AddError --
+// AddError -- add the error, the original value is kept
 func (m *Messages) AddError(err error) {
-	if err != nil {
-		m.Add("%s", err)
+	m.AddErrorEx(MessageSeverityError, err)
+}
+
+// AddErrorEx -- add the error with the severity
+func (m *Messages) AddErrorEx(severity MessageSeverity, err error) {
+	if err == nil {
+		return
 	}
+
+	m.check()
+
+	m.Lock()
+	defer m.Unlock()
+
+	m.entries = append(m.entries, messageEntry{severity: severity, err: err})
 }
 
+// Content -- copy of the error messages texts
 func (m *Messages) Content() []string {
-	return m.s
+	return slices.Collect(m.Texts(MessageSeverityError))
 }
 
-// String --
-func (m *Messages) String(separators ...string) string {
-	m.RLock()
-	defer m.RUnlock()
+// Warnings -- copy of the warning messages texts
+func (m *Messages) Warnings() []string {
+	return slices.Collect(m.Texts(MessageSeverityWarning))
+}
+
+// All -- iterator over the snapshot of all messages in the order of adding
+func (m *Messages) All() iter.Seq2[MessageSeverity, error] {
+	entries := m.snapshot()
+
+	return func(yield func(MessageSeverity, error) bool) {
+		for _, e := range entries {
+			if !yield(e.severity, e.err) {
+				return
+			}
+		}
+	}
+}
+
+// Texts -- iterator over the snapshot of the messages texts with the severity
+func (m *Messages) Texts(severity MessageSeverity) iter.Seq[string] {
+	all := m.All()
+
+	return func(yield func(string) bool) {
+		for sev, err := range all {
+			if sev == severity && !yield(err.Error()) {
+				return
+			}
+		}
+	}
+}
+
+func (m *Messages) errors(severity MessageSeverity) []error {
+	var list []error
+	for sev, err := range m.All() {
+		if sev == severity {
+			list = append(list, err)
+		}
+	}
+	return list
+}
 
-	if len(m.s) == 0 {
+// String -- error messages joined by the separator ("; " by default)
+func (m *Messages) String(separators ...string) string {
+	err := m.Error(separators...)
+	if err == nil {
 		return ""
 	}
 
+	return err.Error()
+}
+
+// Error -- collected errors as *MultiError, nil if there are no errors (warnings are ignored)
+func (m *Messages) Error(separators ...string) error {
+	return makeMultiError(m.errors(MessageSeverityError), separators)
+}
+
+// Warning -- collected warnings as *MultiError, nil if there are no warnings
+func (m *Messages) Warning(separators ...string) error {
+	return makeMultiError(m.errors(MessageSeverityWarning), separators)
+}
+
+func makeMultiError(errs []error, separators []string) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
 	sep := "; "
 	if len(separators) != 0 {
 		sep = strings.Join(separators, "")
 	}
 
-	return strings.Join(m.s, sep)
+	return &MultiError{
+		errs: errs,
+		sep:  sep,
+	}
 }
 
 // Error --
-func (m *Messages) Error(separators ...string) error {
-	s := m.String(separators...)
-	if s == "" {
-		return nil
+func (e *MultiError) Error() string {
+	var s strings.Builder
+	for i, err := range e.errs {
+		if i > 0 {
+			s.WriteString(e.sep)
+		}
+		s.WriteString(err.Error())
 	}
+	return s.String()
+}
+
+// Unwrap -- for errors.Is/As
+func (e *MultiError) Unwrap() []error {
+	return e.errs
+}
 
-	return errors.New(s)
+// Errors -- combined errors
+func (e *MultiError) Errors() []error {
+	return slices.Clone(e.errs)
 }
 
 //----------------------------------------------------------------------------------------------------------------------------//
 
-// LogProcessingTime  --
+// LogProcessingTime -- write the time elapsed from t0 if the level is enabled by the facility verbosity, returns the current time
 func LogProcessingTime(facility string, level string, id uint64, module string, message string, t0 int64) int64 {
 	if level == "" {
-		level = "TM"
+		level = LogLevelTime.String()
+	}
+
+	now := NowUnixNano()
+
+	if l, ok := ParseLogLevel(level); ok && !IsLogEnabled(facility, l) {
+		return now
 	}
 
 	if message == "" {
@@ -624,9 +527,8 @@ func LogProcessingTime(facility string, level string, id uint64, module string,
 		}
 	}
 
-	now := NowUnixNano()
 	duration := now - t0
-	Logger(facility, level, "%s%s %d.%03d ms", prefix, message, duration/int64(time.Millisecond), (duration%int64(time.Millisecond))/1000)
+	GetLogger()(facility, level, "%s%s %d.%03d ms", prefix, message, duration/int64(time.Millisecond), (duration%int64(time.Millisecond))/1000)
 	return now
 }
 
@@ -0,0 +1,306 @@
+package misc
+
+import (
+	"net"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type (
+	// NetInterface -- network interface with its addresses
+	NetInterface struct {
+		Index     int            `json:"index"`
+		Name      string         `json:"name"`
+		MAC       string         `json:"mac,omitempty"`
+		MTU       int            `json:"mtu"`
+		Flags     net.Flags      `json:"-"`
+		Up        bool           `json:"up"`
+		Loopback  bool           `json:"loopback"`
+		Multicast bool           `json:"multicast"`
+		Prefixes  []netip.Prefix `json:"prefixes"`
+	}
+
+	// NetAddrScope -- address classification
+	NetAddrScope int
+
+	// NetAddrFilter -- addresses filter. All false in the group means no filtering by the group
+	NetAddrFilter struct {
+		// Families
+		IPv4 bool
+		IPv6 bool
+
+		// Scopes
+		Loopback  bool
+		LinkLocal bool
+		Private   bool
+		Global    bool
+
+		// Only the interfaces in the up state
+		UpOnly bool
+	}
+)
+
+// Address scopes
+const (
+	NetScopeOther NetAddrScope = iota // unspecified, multicast etc.
+	NetScopeLoopback
+	NetScopeLinkLocal
+	NetScopePrivate
+	NetScopeGlobal
+)
+
+const (
+	// DefaultNetInterfacesTTL -- lifetime of the cached interfaces snapshot
+	DefaultNetInterfacesTTL = 30 * time.Second
+
+	// the snapshot is refreshed on IsMyAddr miss if it is older
+	netInterfacesMissRefresh = time.Second
+)
+
+var (
+	netInterfacesMutex sync.Mutex
+	netInterfacesCache []NetInterface
+	netInterfacesTS    time.Time
+	netInterfacesTTL   = DefaultNetInterfacesTTL
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// NetAddrScopeOf --
+func NetAddrScopeOf(addr netip.Addr) NetAddrScope {
+	switch {
+	case addr.IsLoopback():
+		return NetScopeLoopback
+	case addr.IsLinkLocalUnicast():
+		return NetScopeLinkLocal
+	case addr.IsPrivate():
+		return NetScopePrivate
+	case addr.IsGlobalUnicast():
+		return NetScopeGlobal
+	default:
+		return NetScopeOther
+	}
+}
+
+// Match -- does the interface address satisfy the filter? nil filter matches all
+func (f *NetAddrFilter) Match(iface *NetInterface, addr netip.Addr) bool {
+	if f == nil {
+		return true
+	}
+
+	if f.UpOnly && !iface.Up {
+		return false
+	}
+
+	if (f.IPv4 || f.IPv6) && !((f.IPv4 && addr.Is4()) || (f.IPv6 && addr.Is6())) {
+		return false
+	}
+
+	if !(f.Loopback || f.LinkLocal || f.Private || f.Global) {
+		return true
+	}
+
+	switch NetAddrScopeOf(addr) {
+	case NetScopeLoopback:
+		return f.Loopback
+	case NetScopeLinkLocal:
+		return f.LinkLocal
+	case NetScopePrivate:
+		return f.Private
+	case NetScopeGlobal:
+		return f.Global
+	default:
+		return false
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetNetInterfacesTTL -- lifetime of the cached snapshot used by NetInterfaces, MyAddrs and IsMyAddr
+func SetNetInterfacesTTL(ttl time.Duration) {
+	netInterfacesMutex.Lock()
+	defer netInterfacesMutex.Unlock()
+
+	netInterfacesTTL = ttl
+}
+
+// NetInterfaces -- cached snapshot of the network interfaces, it is refreshed when expired
+func NetInterfaces() ([]NetInterface, error) {
+	return netInterfaces(-1)
+}
+
+// RefreshNetInterfaces -- enumerate the network interfaces and refresh the cached snapshot
+func RefreshNetInterfaces() ([]NetInterface, error) {
+	return netInterfaces(0)
+}
+
+// netInterfaces -- maxAge < 0 -- the configured TTL
+func netInterfaces(maxAge time.Duration) ([]NetInterface, error) {
+	netInterfacesMutex.Lock()
+	defer netInterfacesMutex.Unlock()
+
+	if maxAge < 0 {
+		maxAge = netInterfacesTTL
+	}
+
+	if netInterfacesCache == nil || time.Since(netInterfacesTS) >= maxAge {
+		list, err := loadNetInterfaces()
+		if err != nil {
+			return nil, err
+		}
+
+		netInterfacesCache = list
+		netInterfacesTS = time.Now()
+	}
+
+	return cloneNetInterfaces(netInterfacesCache), nil
+}
+
+func loadNetInterfaces() ([]NetInterface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]NetInterface, 0, len(ifaces))
+
+	for _, i := range ifaces {
+		addrs, err := i.Addrs()
+		if err != nil {
+			return nil, err
+		}
+
+		iface := NetInterface{
+			Index:     i.Index,
+			Name:      i.Name,
+			MAC:       i.HardwareAddr.String(),
+			MTU:       i.MTU,
+			Flags:     i.Flags,
+			Up:        i.Flags&net.FlagUp != 0,
+			Loopback:  i.Flags&net.FlagLoopback != 0,
+			Multicast: i.Flags&net.FlagMulticast != 0,
+			Prefixes:  make([]netip.Prefix, 0, len(addrs)),
+		}
+
+		for _, addr := range addrs {
+			var (
+				ip   net.IP
+				bits = -1
+			)
+
+			switch v := addr.(type) {
+			case *net.IPNet:
+				ip = v.IP
+				bits, _ = v.Mask.Size()
+			case *net.IPAddr:
+				ip = v.IP
+			}
+
+			a, ok := netip.AddrFromSlice(ip)
+			if !ok {
+				continue
+			}
+			a = a.Unmap()
+
+			switch {
+			case bits < 0:
+				bits = a.BitLen()
+			case a.Is4() && bits > 32:
+				bits -= 96 // IPv4 with 16-byte mask
+			}
+
+			iface.Prefixes = append(iface.Prefixes, netip.PrefixFrom(a, bits))
+		}
+
+		list = append(list, iface)
+	}
+
+	return list, nil
+}
+
+func cloneNetInterfaces(list []NetInterface) []NetInterface {
+	list = slices.Clone(list)
+	for i := range list {
+		list[i].Prefixes = slices.Clone(list[i].Prefixes)
+	}
+	return list
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// MyAddrs -- addresses (with the prefix length) of the interfaces satisfying the filter, from the cached snapshot
+func MyAddrs(filter *NetAddrFilter) ([]netip.Prefix, error) {
+	ifaces, err := NetInterfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var list []netip.Prefix
+	for i := range ifaces {
+		for _, p := range ifaces[i].Prefixes {
+			if filter.Match(&ifaces[i], p.Addr()) {
+				list = append(list, p)
+			}
+		}
+	}
+
+	return list, nil
+}
+
+// IsMyAddr -- does the address belong to the local interfaces? The cached snapshot is used, on a miss it is refreshed if it isn't fresh
+func IsMyAddr(addr netip.Addr) (bool, error) {
+	addr = addr.Unmap().WithZone("")
+
+	for _, maxAge := range []time.Duration{-1, netInterfacesMissRefresh} {
+		ifaces, err := netInterfaces(maxAge)
+		if err != nil {
+			return false, err
+		}
+
+		for _, iface := range ifaces {
+			for _, p := range iface.Prefixes {
+				if p.Addr() == addr {
+					return true, nil
+				}
+			}
+		}
+	}
+
+	return false, nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// GetMyIPs -- addresses of all interfaces as strings, the interfaces are enumerated on each call
+func GetMyIPs() (map[string]bool, error) {
+	ifaces, err := RefreshNetInterfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make(map[string]bool)
+
+	for _, iface := range ifaces {
+		for _, p := range iface.Prefixes {
+			list[p.Addr().String()] = true
+		}
+	}
+
+	return list, nil
+}
+
+// IsMyIP -- does the address belong to the local interfaces? See IsMyAddr
+func IsMyIP(ip string) (bool, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false, nil
+	}
+
+	return IsMyAddr(addr)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,249 @@
+package misc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type (
+	// PathRootFunc -- returns the directory of the named path root. The result may use any AbsPathEx syntax, relative paths are resolved against
+	// the application executable directory
+	PathRootFunc func() (string, error)
+)
+
+const (
+	maxPathRootDepth = 8
+)
+
+var (
+	pathRootsMutex sync.RWMutex
+	pathRoots      = map[string]PathRootFunc{
+		"home": os.UserHomeDir,
+		"tmp":  func() (string, error) { return os.TempDir(), nil },
+		"exec": func() (string, error) { return AppExecPath(), nil },
+		"work": func() (string, error) { return AppWorkDir(), nil },
+		"cwd":  os.Getwd,
+
+		"config": appSubdir(os.UserConfigDir),
+		"cache":  appSubdir(os.UserCacheDir),
+		"data":   appSubdir(xdgDataHome),
+		"state":  appSubdir(xdgStateHome),
+
+		"xdg_config":  os.UserConfigDir,
+		"xdg_cache":   os.UserCacheDir,
+		"xdg_data":    xdgDataHome,
+		"xdg_state":   xdgStateHome,
+		"xdg_runtime": xdgRuntimeDir,
+	}
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// RegisterPathRoot -- register the named path root used by AbsPathEx as "{name}/...". Built-in roots can be replaced:
+//
+//	home, tmp, exec (application executable directory), work (application work directory), cwd (current directory),
+//	config, cache, data, state (per application subdirectories of the XDG ones),
+//	xdg_config, xdg_cache, xdg_data, xdg_state, xdg_runtime
+func RegisterPathRoot(name string, f PathRootFunc) error {
+	if name == "" || strings.ContainsAny(name, "{}/\\") {
+		return fmt.Errorf(`illegal path root name "%s"`, name)
+	}
+
+	if f == nil {
+		return fmt.Errorf(`path root "%s": nil function`, name)
+	}
+
+	pathRootsMutex.Lock()
+	defer pathRootsMutex.Unlock()
+
+	pathRoots[name] = f
+	return nil
+}
+
+// SetPathRoot -- register the named path root with the fixed directory, it may refer to other roots: SetPathRoot("logs", "{state}/logs")
+func SetPathRoot(name string, dir string) error {
+	return RegisterPathRoot(name, func() (string, error) { return dir, nil })
+}
+
+// PathRoot -- absolute directory of the named path root
+func PathRoot(name string) (string, error) {
+	return pathRoot(name, 0)
+}
+
+// PathRoots -- names of the registered path roots
+func PathRoots() []string {
+	pathRootsMutex.RLock()
+	defer pathRootsMutex.RUnlock()
+
+	list := make([]string, 0, len(pathRoots))
+	for name := range pathRoots {
+		list = append(list, name)
+	}
+
+	slices.Sort(list)
+	return list
+}
+
+func pathRoot(name string, depth int) (string, error) {
+	pathRootsMutex.RLock()
+	f, exists := pathRoots[name]
+	pathRootsMutex.RUnlock()
+
+	if !exists {
+		return "", fmt.Errorf(`unknown path root "{%s}"`, name)
+	}
+
+	dir, err := f()
+	if err != nil {
+		return "", fmt.Errorf(`path root "{%s}": %w`, name, err)
+	}
+
+	return absPath(dir, AppWorkDir(), depth+1, true)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// absPath -- expandEnv is false for the untrusted names, see PathResolver
+func absPath(name string, base string, depth int, expandEnv bool) (string, error) {
+	if depth > maxPathRootDepth {
+		return "", fmt.Errorf(`path "%s": too deep path roots nesting`, name)
+	}
+
+	if expandEnv {
+		var err error
+		name, err = expandPathEnv(name)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	switch {
+	case name == "~" || strings.HasPrefix(name, "~/") || strings.HasPrefix(name, "~"+string(filepath.Separator)):
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		name = home + "/" + name[1:]
+
+	case strings.HasPrefix(name, "{"):
+		root, rest, ok := strings.Cut(name[1:], "}")
+		if !ok {
+			return "", fmt.Errorf(`path "%s": unclosed path root`, name)
+		}
+		if rest != "" && rest[0] != '/' && rest[0] != filepath.Separator {
+			return "", fmt.Errorf(`path "%s": path root must be followed by a separator`, name)
+		}
+
+		dir, err := pathRoot(root, depth)
+		if err != nil {
+			return "", err
+		}
+		name = dir + rest
+
+	case strings.HasPrefix(name, "@"):
+		name = appWorkDir + "/" + name[1:]
+
+	case strings.HasPrefix(name, "$"):
+		d, _ := os.Getwd()
+		name = d + "/" + name[1:]
+
+	case strings.HasPrefix(name, "^"):
+		name = base + "/" + name[1:]
+
+	default:
+		if !filepath.IsAbs(name) {
+			name = AppExecPath() + "/" + name
+		}
+	}
+
+	return filepath.Abs(name)
+}
+
+// expandPathEnv -- expand ${VAR}, undefined variables are errors. "$" without "{" is kept as is ("$" prefix, "$Recycle.Bin" etc.)
+func expandPathEnv(name string) (string, error) {
+	if !strings.Contains(name, "${") {
+		return name, nil
+	}
+
+	var b strings.Builder
+
+	for {
+		i := strings.Index(name, "${")
+		if i < 0 {
+			b.WriteString(name)
+			break
+		}
+
+		b.WriteString(name[:i])
+
+		v, rest, ok := strings.Cut(name[i+2:], "}")
+		if !ok {
+			return "", fmt.Errorf(`path "%s": unclosed "${"`, name)
+		}
+
+		value, exists := os.LookupEnv(v)
+		if !exists {
+			return "", fmt.Errorf("undefined environment variable %s", v)
+		}
+
+		b.WriteString(value)
+		name = rest
+	}
+
+	return b.String(), nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func appSubdir(f PathRootFunc) PathRootFunc {
+	return func() (string, error) {
+		dir, err := f()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, AppName()), nil
+	}
+}
+
+func xdgDir(env string, windowsEnv string, def string) PathRootFunc {
+	return func() (string, error) {
+		if dir := os.Getenv(env); dir != "" && filepath.IsAbs(dir) {
+			return dir, nil
+		}
+
+		if runtime.GOOS == "windows" {
+			if dir := os.Getenv(windowsEnv); dir != "" {
+				return dir, nil
+			}
+			return "", fmt.Errorf("%%%s%% is not defined", windowsEnv)
+		}
+
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, def), nil
+	}
+}
+
+var (
+	xdgDataHome  = xdgDir("XDG_DATA_HOME", "LocalAppData", ".local/share")
+	xdgStateHome = xdgDir("XDG_STATE_HOME", "LocalAppData", ".local/state")
+)
+
+func xdgRuntimeDir() (string, error) {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		return "", fmt.Errorf("$XDG_RUNTIME_DIR is not defined")
+	}
+	return dir, nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
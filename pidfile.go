@@ -0,0 +1,265 @@
+package misc
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type (
+	// PidFile -- locked PID file
+	PidFile struct {
+		mutex      sync.Mutex
+		name       string
+		f          *os.File
+		handedOver bool
+	}
+)
+
+const (
+	// name prefix of the PID files passed to the restarted application
+	pidFileInheritPrefix = "pidfile:"
+
+	// attempts to lock the file while other instances remove and create it
+	maxPidFileAttempts = 8
+)
+
+var (
+	pidFilesMutex sync.Mutex
+	pidFiles      = make(map[string]*PidFile)
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// CreatePidFile -- create the PID file and take the exclusive lock on it, so only one instance of the application can run.
+// The name is resolved by AbsPath, "" -- <AppName>.pid in the application directory.
+// The file is removed by the finalizer. Errors are *Error with ExAlreadyRunning or ExPidFileError code.
+// RestartApp passes the locked file to the new process, its CreatePidFile with the same name adopts the lock
+func CreatePidFile(name string) (p *PidFile, err error) {
+	if name == "" {
+		name = AppName() + ".pid"
+	}
+
+	name, err = AbsPath(name)
+	if err != nil {
+		return nil, MakeError(ExPidFileError, "pid file: %s", err)
+	}
+
+	if f := adoptPidFile(name); f != nil {
+		err = writePid(f)
+		if err != nil {
+			f.Close()
+			return nil, MakeError(ExPidFileError, "pid file %s: %s", name, err)
+		}
+
+		logMessage(LogLevelInfo, "Pid file %s is taken over from the parent process", name)
+		return registerPidFile(name, f), nil
+	}
+
+	f, oldPid, err := openLockedPidFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			f.Close()
+		}
+	}()
+
+	if oldPid > 0 && oldPid != os.Getpid() {
+		if processExists(oldPid) && !lockSupported {
+			err = MakeError(ExAlreadyRunning, "pid file %s: application is already running (pid %d)", name, oldPid)
+			return nil, err
+		}
+
+		logMessage(LogLevelNotice, "Stale pid file %s (pid %d) found", name, oldPid)
+	}
+
+	err = writePid(f)
+	if err != nil {
+		return nil, MakeError(ExPidFileError, "pid file %s: %s", name, err)
+	}
+
+	return registerPidFile(name, f), nil
+}
+
+// openLockedPidFile -- open and lock the file. The lock on the file removed by the previous owner in the meantime protects nothing,
+// so the file is reopened if the locked one isn't the file with the name anymore
+func openLockedPidFile(name string) (f *os.File, oldPid int, err error) {
+	for i := 0; i < maxPidFileAttempts; i++ {
+		f, err = os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, 0, MakeError(ExPidFileError, "pid file: %s", err)
+		}
+
+		oldPid, _ = readPid(f)
+
+		err = lockFile(f)
+		if err != nil {
+			f.Close()
+			if errors.Is(err, errFileLocked) {
+				return nil, 0, MakeError(ExAlreadyRunning, "pid file %s: application is already running (pid %d)", name, oldPid)
+			}
+			return nil, 0, MakeError(ExPidFileError, "pid file %s: %s", name, err)
+		}
+
+		if isSameFile(f, name) {
+			return f, oldPid, nil
+		}
+
+		f.Close()
+	}
+
+	return nil, 0, MakeError(ExPidFileError, "pid file %s: it is removed and created by another process repeatedly", name)
+}
+
+// isSameFile -- is the open file still the file with the name?
+func isSameFile(f *os.File, name string) bool {
+	fi1, err1 := f.Stat()
+	fi2, err2 := os.Stat(name)
+	return err1 == nil && err2 == nil && os.SameFile(fi1, fi2)
+}
+
+func writePid(f *os.File) (err error) {
+	err = f.Truncate(0)
+	if err == nil {
+		_, err = f.WriteAt([]byte(strconv.Itoa(os.Getpid())+EOS), 0)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	return
+}
+
+func registerPidFile(name string, f *os.File) *PidFile {
+	p := &PidFile{
+		name: name,
+		f:    f,
+	}
+
+	pidFilesMutex.Lock()
+	pidFiles[name] = p
+	pidFilesMutex.Unlock()
+
+	AddFinalizer("pidfile:"+name, func(code int, param any) { p.Remove() }, nil)
+
+	return p
+}
+
+// adoptPidFile -- locked file inherited from the parent, nil if there is no one or it isn't the file with the name anymore
+func adoptPidFile(name string) *os.File {
+	f := InheritedFile(pidFileInheritPrefix + name)
+	if f == nil {
+		return nil
+	}
+
+	if !isSameFile(f, name) {
+		f.Close()
+		return nil
+	}
+
+	// the inherited descriptor shares the lock with the parent one, the lock remains after the parent closes its copy
+	if lockFile(f) != nil {
+		f.Close()
+		return nil
+	}
+
+	return f
+}
+
+// restartPidFiles -- locked files to pass to the restarted application
+func restartPidFiles() map[string]*PidFile {
+	pidFilesMutex.Lock()
+	defer pidFilesMutex.Unlock()
+
+	list := make(map[string]*PidFile, len(pidFiles))
+	for name, p := range pidFiles {
+		list[pidFileInheritPrefix+name] = p
+	}
+	return list
+}
+
+func (p *PidFile) file() *os.File {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.f
+}
+
+// handOver -- the file belongs to the restarted application now, Remove only closes it
+func (p *PidFile) handOver() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.handedOver = true
+}
+
+// Name -- full name of the file
+func (p *PidFile) Name() string {
+	return p.name
+}
+
+// Remove -- remove the file and release the lock. The file handed over to the restarted application is only closed
+func (p *PidFile) Remove() (err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.f == nil {
+		return nil
+	}
+
+	DelFinalizer("pidfile:" + p.name)
+
+	pidFilesMutex.Lock()
+	if pidFiles[p.name] == p {
+		delete(pidFiles, p.name)
+	}
+	pidFilesMutex.Unlock()
+
+	// removed while the lock is held: the instance locking the old file after the close sees it isn't the file with the name
+	if !p.handedOver {
+		err = os.Remove(p.name)
+	}
+	if e := p.f.Close(); err == nil {
+		err = e
+	}
+	p.f = nil
+
+	return
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// ReadPidFile -- read PID from the file, the name is resolved by AbsPath
+func ReadPidFile(name string) (pid int, err error) {
+	name, err = AbsPath(name)
+	if err != nil {
+		return
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	return readPid(f)
+}
+
+func readPid(f *os.File) (pid int, err error) {
+	b := make([]byte, 32)
+
+	n, err := f.ReadAt(b, 0)
+	if n == 0 && err != nil {
+		return
+	}
+
+	return strconv.Atoi(strings.TrimSpace(string(b[:n])))
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,31 @@
+// +build !windows
+
+package misc
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+const lockSupported = true
+
+var errFileLocked = errors.New("file is locked")
+
+func lockFile(f *os.File) error {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return errFileLocked
+	}
+
+	return err
+}
+
+func processExists(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,31 @@
+// +build windows
+
+package misc
+
+import (
+	"errors"
+	"os"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// locking is not supported, running instance is detected by the process existence
+const lockSupported = false
+
+var errFileLocked = errors.New("file is locked")
+
+func lockFile(f *os.File) error {
+	return nil
+}
+
+func processExists(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	p.Release()
+	return true
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
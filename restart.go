@@ -0,0 +1,339 @@
+package misc
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Restart protocol:
+//   - the parent starts AppFullName() with the same arguments and environment;
+//   - inherited files are passed as fd 3, 4, ... and their names are listed in the EnvRestartFiles variable (comma separated);
+//   - the write end of the pipe is passed as the fd from EnvRestartReadyFD, the child writes "ready" into it;
+//   - the child reads both variables at startup and removes them from its environment;
+//   - after the child reported readiness the parent stops by the usual StopApp/finalizers path.
+
+const (
+	// EnvRestartFiles -- names of the inherited files
+	EnvRestartFiles = "MISC_RESTART_FILES"
+	// EnvRestartReadyFD -- descriptor for the readiness notification
+	EnvRestartReadyFD = "MISC_RESTART_READY_FD"
+
+	// DefaultRestartReadyTimeout --
+	DefaultRestartReadyTimeout = 30 * time.Second
+
+	restartReadyMessage = "ready"
+)
+
+type (
+	// RestartOptions --
+	RestartOptions struct {
+		// Listeners passed to the child by names. They must have the File() method (*net.TCPListener, *net.UnixListener)
+		Listeners map[string]net.Listener
+		// Files passed to the child by names. The PID files created by CreatePidFile are passed automatically
+		Files map[string]*os.File
+		// Arguments of the child, nil -- the same as the current ones
+		Args []string
+		// Additional environment variables of the child ("NAME=value")
+		Env []string
+		// Timeout of waiting for the child readiness, 0 -- DefaultRestartReadyTimeout
+		ReadyTimeout time.Duration
+	}
+
+	filer interface {
+		File() (*os.File, error)
+	}
+
+	restartEnv struct {
+		restarted bool
+		files     []string
+		readyFD   int
+		readyErr  error
+	}
+)
+
+var (
+	// read before any init and removed from the environment, so the processes started by the application don't inherit them
+	inheritedEnv = loadRestartEnv()
+
+	inheritedMutex sync.Mutex
+	inheritedFiles map[string]*os.File // not taken yet
+
+	restartNotifyOnce sync.Once
+	restartNotifyErr  error
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func loadRestartEnv() (e restartEnv) {
+	files := os.Getenv(EnvRestartFiles)
+	fd, exists := os.LookupEnv(EnvRestartReadyFD)
+
+	os.Unsetenv(EnvRestartFiles)
+	os.Unsetenv(EnvRestartReadyFD)
+
+	e.readyFD = -1
+
+	if !exists {
+		return
+	}
+
+	e.restarted = true
+
+	if files != "" {
+		e.files = strings.Split(files, ",")
+	}
+
+	n, err := strconv.Atoi(fd)
+	switch {
+	case err != nil:
+		e.readyErr = fmt.Errorf(`%s: %w`, EnvRestartReadyFD, err)
+	case n < 3:
+		e.readyErr = fmt.Errorf(`%s: illegal descriptor %d`, EnvRestartReadyFD, n)
+	default:
+		e.readyFD = n
+	}
+
+	return
+}
+
+func init() {
+	if inheritedEnv.restarted {
+		DefaultApp().Subscribe(
+			func(from LifecycleState, to LifecycleState) {
+				if to == StateReady {
+					NotifyRestartReady()
+				}
+			},
+		)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Restart -- start the new copy of the application, pass it the listeners and files, wait for its readiness
+// and stop the current one. Not supported on Windows
+func (a *App) Restart(opts RestartOptions) (err error) {
+	if runtime.GOOS == "windows" {
+		return errors.New("restart is not supported on windows")
+	}
+
+	if !a.Started() {
+		return errors.New("application is already stopped")
+	}
+
+	names := make([]string, 0, len(opts.Listeners)+len(opts.Files))
+	files := make([]*os.File, 0, cap(names)+1)
+	owned := make([]*os.File, 0, cap(names)+1)
+
+	defer func() {
+		for _, f := range owned {
+			f.Close()
+		}
+	}()
+
+	for name, l := range opts.Listeners {
+		fl, ok := l.(filer)
+		if !ok {
+			return fmt.Errorf(`listener "%s" (%T) cannot be passed to the child`, name, l)
+		}
+
+		f, err := fl.File()
+		if err != nil {
+			return fmt.Errorf(`listener "%s": %w`, name, err)
+		}
+
+		names = append(names, name)
+		files = append(files, f)
+		owned = append(owned, f)
+	}
+
+	for name, f := range opts.Files {
+		names = append(names, name)
+		files = append(files, f)
+	}
+
+	// locked PID files, the child adopts them in CreatePidFile
+	pidFiles := restartPidFiles()
+	for name, p := range pidFiles {
+		f := p.file()
+		if f == nil || strings.Contains(name, ",") {
+			delete(pidFiles, name)
+			continue
+		}
+		names = append(names, name)
+		files = append(files, f)
+	}
+
+	for _, name := range names {
+		if strings.ContainsAny(name, ",") {
+			return fmt.Errorf(`illegal file name "%s"`, name)
+		}
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	defer w.Close()
+	files = append(files, w)
+
+	args := opts.Args
+	if args == nil {
+		args = os.Args[1:]
+	}
+
+	env := make([]string, 0, len(os.Environ())+len(opts.Env)+2)
+	for _, v := range os.Environ() {
+		if !strings.HasPrefix(v, EnvRestartFiles+"=") && !strings.HasPrefix(v, EnvRestartReadyFD+"=") {
+			env = append(env, v)
+		}
+	}
+	env = append(env, opts.Env...)
+	env = append(env,
+		EnvRestartFiles+"="+strings.Join(names, ","),
+		EnvRestartReadyFD+"="+strconv.Itoa(3+len(files)-1),
+	)
+
+	cmd := exec.Command(AppFullName(), args...)
+	cmd.Env = env
+	cmd.Dir = AppWorkDir()
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = files
+
+	err = cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	logMessage(LogLevelInfo, "Restart: child process %d started", cmd.Process.Pid)
+
+	// the child has its own copy, the parent must close it to get EOF when the child exits
+	w.Close()
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	ready := make(chan error, 1)
+	go func() {
+		s, err := bufio.NewReader(r).ReadString('\n')
+		if err == nil && strings.TrimSpace(s) != restartReadyMessage {
+			err = fmt.Errorf(`unexpected message "%s"`, strings.TrimSpace(s))
+		}
+		ready <- err
+	}()
+
+	timeout := opts.ReadyTimeout
+	if timeout <= 0 {
+		timeout = DefaultRestartReadyTimeout
+	}
+
+	select {
+	case err = <-ready:
+		if err != nil {
+			cmd.Process.Kill()
+			return fmt.Errorf("restart: child process %d did not report readiness: %w", cmd.Process.Pid, err)
+		}
+
+	case err = <-exited:
+		return fmt.Errorf("restart: child process %d exited: %v", cmd.Process.Pid, err)
+
+	case <-a.clock().After(timeout):
+		cmd.Process.Kill()
+		return fmt.Errorf("restart: child process %d readiness timeout %s", cmd.Process.Pid, timeout)
+	}
+
+	logMessage(LogLevelInfo, "Restart: child process %d is ready", cmd.Process.Pid)
+
+	for _, p := range pidFiles {
+		p.handOver()
+	}
+
+	a.StopEx(0, fmt.Sprintf("restarted as process %d", cmd.Process.Pid))
+	return nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// RestartApp -- restart the application, see App.Restart
+func RestartApp(opts RestartOptions) error {
+	return DefaultApp().Restart(opts)
+}
+
+// IsRestarted -- is the application started by RestartApp?
+func IsRestarted() bool {
+	return inheritedEnv.restarted
+}
+
+// InheritedFile -- file passed by the parent, nil if it doesn't exist or is already taken.
+// Each name can be taken only once, the caller owns the file and closes it
+func InheritedFile(name string) *os.File {
+	inheritedMutex.Lock()
+	defer inheritedMutex.Unlock()
+
+	if inheritedFiles == nil {
+		inheritedFiles = make(map[string]*os.File, len(inheritedEnv.files))
+		for i, name := range inheritedEnv.files {
+			inheritedFiles[name] = os.NewFile(uintptr(3+i), name)
+		}
+	}
+
+	f := inheritedFiles[name]
+	delete(inheritedFiles, name)
+	return f
+}
+
+// InheritedListener -- listener passed by the parent, nil if it doesn't exist or is already taken (see InheritedFile)
+func InheritedListener(name string) (net.Listener, error) {
+	f := InheritedFile(name)
+	if f == nil {
+		return nil, nil
+	}
+
+	l, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf(`inherited listener "%s": %w`, name, err)
+	}
+
+	f.Close()
+	return l, nil
+}
+
+// NotifyRestartReady -- notify the parent that the application is ready.
+// It is called automatically when the application lifecycle becomes ready
+func NotifyRestartReady() error {
+	restartNotifyOnce.Do(func() {
+		if !inheritedEnv.restarted {
+			return
+		}
+
+		if inheritedEnv.readyErr != nil {
+			restartNotifyErr = inheritedEnv.readyErr
+			return
+		}
+
+		f := os.NewFile(uintptr(inheritedEnv.readyFD), "restart-ready")
+		defer f.Close()
+
+		_, restartNotifyErr = f.WriteString(restartReadyMessage + "\n")
+	})
+
+	return restartNotifyErr
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,184 @@
+package misc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type (
+	// PathResolver -- resolves the untrusted names (from configs, API calls etc.) confined to the root directory
+	PathResolver struct {
+		root    string // symlinks evaluated
+		rootAbs string // as specified
+	}
+
+	// PathEscapeError -- the name resolves outside the root
+	PathEscapeError struct {
+		Root    string
+		Name    string
+		Path    string
+		Symlink bool // escapes through a symlink
+	}
+)
+
+var (
+	// ErrPathEscape -- errors.Is(err, ErrPathEscape) is true for *PathEscapeError
+	ErrPathEscape = errors.New("path escapes the root")
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Error --
+func (e *PathEscapeError) Error() string {
+	via := ""
+	if e.Symlink {
+		via = " via symlink"
+	}
+	return fmt.Sprintf(`"%s" (%s) escapes the root %s%s`, e.Name, e.Path, e.Root, via)
+}
+
+// Unwrap --
+func (e *PathEscapeError) Unwrap() error {
+	return ErrPathEscape
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// NewPathResolver -- resolver confined to the root, root is resolved by AbsPath and must be an existing directory
+func NewPathResolver(root string) (*PathResolver, error) {
+	rootAbs, err := AbsPath(root)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := filepath.EvalSymlinks(rootAbs)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := os.Stat(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", rootAbs)
+	}
+
+	return &PathResolver{
+		root:    r,
+		rootAbs: rootAbs,
+	}, nil
+}
+
+// Root -- root directory with the symlinks evaluated
+func (r *PathResolver) Root() string {
+	return r.root
+}
+
+// Resolve -- absolute path of the name inside the root. The name follows the AbsPathEx rules, but the plain relative names and "^" are relative
+// to the root and the environment variables are not expanded ("${VAR}" is the plain relative name).
+// *PathEscapeError is returned if the result is outside the root lexically or after evaluating the symlinks of its existing part.
+// The check is not atomic, use Open or OpenRoot where the files are changed concurrently
+func (r *PathResolver) Resolve(name string) (string, error) {
+	src := name
+	if name == "" || strings.HasPrefix(name, "${") || (!strings.ContainsAny(name[0:1], "@$^~{") && !filepath.IsAbs(name)) {
+		name = "^" + name
+	}
+
+	p, err := absPath(name, r.root, 0, false)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case isWithin(r.root, p):
+	case r.rootAbs != r.root && isWithin(r.rootAbs, p):
+		p = filepath.Join(r.root, mustRel(r.rootAbs, p))
+	default:
+		return "", &PathEscapeError{Root: r.root, Name: src, Path: p}
+	}
+
+	// the longest existing part
+	existing := p
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+
+		parent := filepath.Dir(existing)
+		if parent == existing || !isWithin(r.root, parent) {
+			return p, nil
+		}
+		existing = parent
+	}
+
+	target, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		// dangling symlink, its target is unknown
+		return "", &PathEscapeError{Root: r.root, Name: src, Path: p, Symlink: true}
+	}
+
+	if !isWithin(r.root, target) {
+		return "", &PathEscapeError{Root: r.root, Name: src, Path: p, Symlink: true}
+	}
+
+	return p, nil
+}
+
+// Rel -- resolved name relative to the root ("." for the root itself), suitable for the os.Root methods
+func (r *PathResolver) Rel(name string) (string, error) {
+	p, err := r.Resolve(name)
+	if err != nil {
+		return "", err
+	}
+
+	return mustRel(r.root, p), nil
+}
+
+// OpenRoot -- os.Root of the root directory, it prevents escaping at the open time
+func (r *PathResolver) OpenRoot() (*os.Root, error) {
+	return os.OpenRoot(r.root)
+}
+
+// Open -- open the file for reading, the name is checked by Resolve and opened by os.OpenInRoot
+func (r *PathResolver) Open(name string) (*os.File, error) {
+	rel, err := r.Rel(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.OpenInRoot(r.root, rel)
+}
+
+// ResolveInRoot -- resolve the name confined to the root, see PathResolver.Resolve
+func ResolveInRoot(root string, name string) (string, error) {
+	r, err := NewPathResolver(root)
+	if err != nil {
+		return "", err
+	}
+
+	return r.Resolve(name)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func isWithin(root string, p string) bool {
+	rel, err := filepath.Rel(root, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+func mustRel(root string, p string) string {
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		return p
+	}
+	return rel
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
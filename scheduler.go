@@ -0,0 +1,281 @@
+package misc
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type (
+	// JobFunc -- periodic job, ctx is canceled when the application stops or the job is unscheduled
+	JobFunc func(ctx context.Context) error
+
+	// MissedRunPolicy -- what to do with the activations missed while the previous run was in progress
+	MissedRunPolicy int
+
+	// JobOptions --
+	JobOptions struct {
+		// Random delay [0, Jitter) added to each activation
+		Jitter time.Duration
+		// Start the next run even if the previous one is still in progress
+		AllowOverlap bool
+		// Missed activations policy
+		MissedRun MissedRunPolicy
+	}
+
+	// JobStatus --
+	JobStatus struct {
+		Name         string        `json:"name"`
+		Schedule     string        `json:"schedule"`
+		Running      int           `json:"running"`
+		Next         time.Time     `json:"next"`
+		LastStart    time.Time     `json:"lastStart"`
+		LastDuration time.Duration `json:"lastDuration"`
+		LastError    string        `json:"lastError,omitempty"`
+		Runs         int64         `json:"runs"`
+		Missed       int64         `json:"missed"`
+	}
+
+	job struct {
+		name     string
+		schedule JobSchedule
+		f        JobFunc
+		opts     JobOptions
+		cancel   context.CancelFunc
+		mutex    sync.Mutex
+		status   JobStatus
+	}
+
+	jobs struct {
+		mutex sync.RWMutex
+		list  map[string]*job
+	}
+)
+
+// Missed activations policies
+const (
+	// MissedRunSkip -- skip the missed activations
+	MissedRunSkip MissedRunPolicy = iota
+	// MissedRunOnce -- run once immediately if any activation was missed
+	MissedRunOnce
+)
+
+const (
+	jobWorkerPrefix = "job:"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// ScheduleJob -- schedule the periodic job. spec is the interval or the cron expression, see ParseJobSchedule
+func (a *App) ScheduleJob(name string, spec string, f JobFunc, opts *JobOptions) error {
+	schedule, err := ParseJobSchedule(spec)
+	if err != nil {
+		return err
+	}
+
+	return a.ScheduleJobEx(name, schedule, f, opts)
+}
+
+// ScheduleJobEx -- schedule the periodic job with the custom schedule
+func (a *App) ScheduleJobEx(name string, schedule JobSchedule, f JobFunc, opts *JobOptions) error {
+	j := &job{
+		name:     name,
+		schedule: schedule,
+		f:        f,
+	}
+
+	if opts != nil {
+		j.opts = *opts
+	}
+
+	j.status.Name = name
+	j.status.Schedule = schedule.String()
+
+	a.jobs.mutex.Lock()
+	defer a.jobs.mutex.Unlock()
+
+	if _, exists := a.jobs.list[name]; exists {
+		return fmt.Errorf(`job "%s" is already scheduled`, name)
+	}
+
+	var ctx context.Context
+	ctx, j.cancel = context.WithCancel(a.Context())
+
+	err := a.Go(jobWorkerPrefix+name,
+		func(context.Context) error {
+			a.runJob(ctx, j)
+			return nil
+		},
+		nil,
+	)
+	if err != nil {
+		j.cancel()
+		return err
+	}
+
+	if a.jobs.list == nil {
+		a.jobs.list = make(map[string]*job, 16)
+	}
+	a.jobs.list[name] = j
+
+	return nil
+}
+
+// UnscheduleJob -- stop the job, the current run gets the canceled context
+func (a *App) UnscheduleJob(name string) bool {
+	a.jobs.mutex.Lock()
+	defer a.jobs.mutex.Unlock()
+
+	j, exists := a.jobs.list[name]
+	if !exists {
+		return false
+	}
+
+	j.cancel()
+	delete(a.jobs.list, name)
+	return true
+}
+
+// Jobs -- status of the scheduled jobs
+func (a *App) Jobs() []JobStatus {
+	a.jobs.mutex.RLock()
+	defer a.jobs.mutex.RUnlock()
+
+	list := make([]JobStatus, 0, len(a.jobs.list))
+	for _, j := range a.jobs.list {
+		j.mutex.Lock()
+		list = append(list, j.status)
+		j.mutex.Unlock()
+	}
+
+	slices.SortFunc(list, func(a, b JobStatus) int { return strings.Compare(a.Name, b.Name) })
+	return list
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func (a *App) runJob(ctx context.Context, j *job) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	next := j.schedule.Next(a.clock().Now())
+
+	for {
+		if next.IsZero() {
+			logMessage(LogLevelInfo, `Job "%s": no more activations`, j.name)
+			return
+		}
+
+		j.mutex.Lock()
+		j.status.Next = next
+		j.mutex.Unlock()
+
+		at := next
+		if j.opts.Jitter > 0 {
+			at = at.Add(rand.N(j.opts.Jitter))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-a.clock().After(at.Sub(a.clock().Now())):
+		}
+
+		if j.opts.AllowOverlap {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				a.callJob(ctx, j)
+			}()
+		} else {
+			a.callJob(ctx, j)
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		now := a.clock().Now()
+		planned := j.schedule.Next(next)
+
+		missed := int64(0)
+		for !planned.IsZero() && !planned.After(now) && missed < 1000 {
+			missed++
+			planned = j.schedule.Next(planned)
+		}
+
+		if missed > 0 {
+			j.mutex.Lock()
+			j.status.Missed += missed
+			j.mutex.Unlock()
+
+			logMessage(LogLevelDebug, `Job "%s": %d activation(s) missed`, j.name, missed)
+
+			if j.opts.MissedRun == MissedRunOnce {
+				planned = now
+			}
+		}
+
+		next = planned
+	}
+}
+
+func (a *App) callJob(ctx context.Context, j *job) {
+	t0 := a.clock().Now()
+
+	j.mutex.Lock()
+	j.status.Running++
+	j.status.LastStart = t0
+	j.mutex.Unlock()
+
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				logMessage(LogLevelError, "Job \"%s\" panicked: %v\n%s", j.name, r, FormatCallStack(GetCallStack(0)))
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+
+		return j.f(ctx)
+	}()
+
+	if err != nil {
+		logMessage(LogLevelWarning, `Job "%s": %s`, j.name, err)
+	}
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.status.Running--
+	j.status.Runs++
+	j.status.LastDuration = a.clock().Now().Sub(t0)
+	j.status.LastError = ""
+	if err != nil {
+		j.status.LastError = err.Error()
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// ScheduleJob -- schedule the periodic job, see App.ScheduleJob
+func ScheduleJob(name string, spec string, f JobFunc, opts *JobOptions) error {
+	return DefaultApp().ScheduleJob(name, spec, f, opts)
+}
+
+// UnscheduleJob --
+func UnscheduleJob(name string) bool {
+	return DefaultApp().UnscheduleJob(name)
+}
+
+// Jobs --
+func Jobs() []JobStatus {
+	return DefaultApp().Jobs()
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
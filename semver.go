@@ -0,0 +1,346 @@
+package misc
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type (
+	// Version -- semantic version (https://semver.org)
+	Version struct {
+		Major      uint64
+		Minor      uint64
+		Patch      uint64
+		Prerelease []string
+		Build      []string
+	}
+
+	// VersionConstraint -- set of the version conditions: ">=1.4, <2 || ^3.1"
+	VersionConstraint struct {
+		src    string
+		groups [][]versionCondition
+	}
+
+	versionCondition struct {
+		op string
+		v  *Version
+	}
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// ParseVersion -- parse the semantic version. Leading "v" is allowed, missing minor and patch parts are zeros ("1.4" is "1.4.0")
+func ParseVersion(s string) (*Version, error) {
+	v, _, err := parseVersion(s)
+	return v, err
+}
+
+// parseVersion -- parts is the number of the numeric parts specified in the source
+func parseVersion(s string) (v *Version, parts int, err error) {
+	src := s
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+
+	v = &Version{}
+
+	s, build, hasBuild := strings.Cut(s, "+")
+	if hasBuild {
+		v.Build, err = versionIdentifiers(build, false)
+		if err != nil {
+			return nil, 0, fmt.Errorf(`version "%s": build: %w`, src, err)
+		}
+	}
+
+	s, pre, hasPre := strings.Cut(s, "-")
+	if hasPre {
+		v.Prerelease, err = versionIdentifiers(pre, true)
+		if err != nil {
+			return nil, 0, fmt.Errorf(`version "%s": prerelease: %w`, src, err)
+		}
+	}
+
+	numbers := strings.Split(s, ".")
+	if len(numbers) > 3 {
+		return nil, 0, fmt.Errorf(`version "%s": too many parts`, src)
+	}
+
+	dst := []*uint64{&v.Major, &v.Minor, &v.Patch}
+	for i, n := range numbers {
+		if n == "" || (len(n) > 1 && n[0] == '0') {
+			return nil, 0, fmt.Errorf(`version "%s": bad number "%s"`, src, n)
+		}
+
+		*dst[i], err = strconv.ParseUint(n, 10, 64)
+		if err != nil {
+			return nil, 0, fmt.Errorf(`version "%s": bad number "%s"`, src, n)
+		}
+	}
+
+	return v, len(numbers), nil
+}
+
+func versionIdentifiers(s string, numericCheck bool) ([]string, error) {
+	list := strings.Split(s, ".")
+
+	for _, id := range list {
+		if id == "" {
+			return nil, fmt.Errorf("empty identifier")
+		}
+
+		numeric := true
+		for _, c := range id {
+			switch {
+			case c >= '0' && c <= '9':
+			case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '-':
+				numeric = false
+			default:
+				return nil, fmt.Errorf(`illegal identifier "%s"`, id)
+			}
+		}
+
+		if numericCheck && numeric && len(id) > 1 && id[0] == '0' {
+			return nil, fmt.Errorf(`leading zero in "%s"`, id)
+		}
+	}
+
+	return list, nil
+}
+
+// MustParseVersion -- ParseVersion which panics on error
+func MustParseVersion(s string) *Version {
+	v, err := ParseVersion(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// String --
+func (v *Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+
+	if len(v.Prerelease) > 0 {
+		s += "-" + strings.Join(v.Prerelease, ".")
+	}
+
+	if len(v.Build) > 0 {
+		s += "+" + strings.Join(v.Build, ".")
+	}
+
+	return s
+}
+
+// Compare -- -1, 0 or 1 by the version precedence, build metadata is ignored
+func (v *Version) Compare(v2 *Version) int {
+	if c := cmp.Compare(v.Major, v2.Major); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(v.Minor, v2.Minor); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(v.Patch, v2.Patch); c != 0 {
+		return c
+	}
+
+	// release is greater than prerelease
+	switch {
+	case len(v.Prerelease) == 0 && len(v2.Prerelease) == 0:
+		return 0
+	case len(v.Prerelease) == 0:
+		return 1
+	case len(v2.Prerelease) == 0:
+		return -1
+	}
+
+	for i := 0; i < min(len(v.Prerelease), len(v2.Prerelease)); i++ {
+		id1, id2 := v.Prerelease[i], v2.Prerelease[i]
+
+		n1, err1 := strconv.ParseUint(id1, 10, 64)
+		n2, err2 := strconv.ParseUint(id2, 10, 64)
+
+		var c int
+		switch {
+		case err1 == nil && err2 == nil:
+			c = cmp.Compare(n1, n2)
+		case err1 == nil:
+			c = -1 // numeric is lower
+		case err2 == nil:
+			c = 1
+		default:
+			c = strings.Compare(id1, id2)
+		}
+
+		if c != 0 {
+			return c
+		}
+	}
+
+	return cmp.Compare(len(v.Prerelease), len(v2.Prerelease))
+}
+
+// CompareVersions -- compare two versions strings
+func CompareVersions(v1 string, v2 string) (int, error) {
+	p1, err := ParseVersion(v1)
+	if err != nil {
+		return 0, err
+	}
+
+	p2, err := ParseVersion(v2)
+	if err != nil {
+		return 0, err
+	}
+
+	return p1.Compare(p2), nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// ParseVersionConstraint -- parse the constraint. Conditions separated by "," must be all true, groups separated by "||" are alternatives.
+// Operators: = (default), !=, >, >=, <, <=, ~ (~1.2.3 -- >=1.2.3, <1.3.0), ^ (^1.2.3 -- >=1.2.3, <2.0.0).
+// Missing version parts are zeros: "<2" is "<2.0.0"
+func ParseVersionConstraint(s string) (*VersionConstraint, error) {
+	c := &VersionConstraint{
+		src: strings.TrimSpace(s),
+	}
+
+	for _, group := range strings.Split(s, "||") {
+		var conds []versionCondition
+
+		for _, cond := range strings.Split(group, ",") {
+			cond = strings.TrimSpace(cond)
+			if cond == "" {
+				return nil, fmt.Errorf(`constraint "%s": empty condition`, c.src)
+			}
+
+			op := ""
+			for _, o := range []string{">=", "<=", "!=", "==", ">", "<", "=", "~", "^"} {
+				if strings.HasPrefix(cond, o) {
+					op = o
+					break
+				}
+			}
+
+			v, parts, err := parseVersion(cond[len(op):])
+			if err != nil {
+				return nil, fmt.Errorf(`constraint "%s": %w`, c.src, err)
+			}
+
+			switch op {
+			case "~":
+				upper := &Version{Major: v.Major, Minor: v.Minor + 1}
+				if parts == 1 {
+					upper = &Version{Major: v.Major + 1}
+				}
+				conds = append(conds, versionCondition{">=", v}, versionCondition{"<", upper})
+
+			case "^":
+				var upper *Version
+				switch {
+				case v.Major > 0 || parts == 1:
+					upper = &Version{Major: v.Major + 1}
+				case v.Minor > 0 || parts == 2:
+					upper = &Version{Minor: v.Minor + 1}
+				default:
+					upper = &Version{Patch: v.Patch + 1}
+				}
+				conds = append(conds, versionCondition{">=", v}, versionCondition{"<", upper})
+
+			case "", "==":
+				conds = append(conds, versionCondition{"=", v})
+
+			default:
+				conds = append(conds, versionCondition{op, v})
+			}
+		}
+
+		c.groups = append(c.groups, conds)
+	}
+
+	return c, nil
+}
+
+// String --
+func (c *VersionConstraint) String() string {
+	return c.src
+}
+
+// Check -- does the version satisfy the constraint?
+func (c *VersionConstraint) Check(v *Version) bool {
+	for _, group := range c.groups {
+		ok := true
+
+		for _, cond := range group {
+			r := v.Compare(cond.v)
+
+			switch cond.op {
+			case "=":
+				ok = r == 0
+			case "!=":
+				ok = r != 0
+			case ">":
+				ok = r > 0
+			case ">=":
+				ok = r >= 0
+			case "<":
+				ok = r < 0
+			case "<=":
+				ok = r <= 0
+			}
+
+			if !ok {
+				break
+			}
+		}
+
+		if ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// AppSemVersion -- semantic version of the running application (AppVersion or the module version from the build info)
+func AppSemVersion() (*Version, error) {
+	s := AppVersion()
+	if s == "debug" {
+		s = GetBuildInfo().Version
+	}
+
+	if f := strings.Fields(s); len(f) > 0 {
+		s = f[0]
+	}
+
+	return ParseVersion(s)
+}
+
+// CheckAppVersion -- check the running application version against the constraint (for example announced by a peer or a config)
+func CheckAppVersion(constraint string) error {
+	c, err := ParseVersionConstraint(constraint)
+	if err != nil {
+		return err
+	}
+
+	v, err := AppSemVersion()
+	if err != nil {
+		return err
+	}
+
+	if !c.Check(v) {
+		return fmt.Errorf(`application version %s does not satisfy "%s"`, v, c)
+	}
+
+	return nil
+}
+
+// RequireMinVersion -- check the running application version is not lower than the minimal required one
+func RequireMinVersion(minVersion string) error {
+	return CheckAppVersion(">=" + minVersion)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,124 @@
+package misc
+
+import (
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type (
+	// SignalHandler -- custom signal handler, replaces the default processing of the signal
+	SignalHandler func(sig os.Signal)
+)
+
+var (
+	signalChan = make(chan os.Signal, 8)
+
+	signalsMutex      sync.RWMutex
+	signalHandlers    = make(map[os.Signal]SignalHandler, 8)
+	signalExitCodes   = make(map[os.Signal]int, 8)
+	signalNumberCodes = false
+	signalForcedExit  = true
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// HandleSignal -- bind the handler to the signal, nil handler restores the default processing
+func HandleSignal(sig os.Signal, handler SignalHandler) {
+	signalsMutex.Lock()
+	defer signalsMutex.Unlock()
+
+	if handler != nil {
+		signalHandlers[sig] = handler
+		signal.Notify(signalChan, sig)
+		return
+	}
+
+	delete(signalHandlers, sig)
+	if !slices.Contains(defaultSignals, sig) {
+		signal.Reset(sig)
+	}
+}
+
+// SetSignalExitCode -- exit code used when the stop signal is received
+func SetSignalExitCode(sig os.Signal, code int) {
+	signalsMutex.Lock()
+	defer signalsMutex.Unlock()
+
+	signalExitCodes[sig] = code
+}
+
+// SetSignalNumberExitCodes -- use 128+signo as the exit code for the stop signals without the code defined by SetSignalExitCode
+func SetSignalNumberExitCodes(enable bool) {
+	signalsMutex.Lock()
+	defer signalsMutex.Unlock()
+
+	signalNumberCodes = enable
+}
+
+// SetSignalForcedExit -- repeated SIGINT forces the immediate exit without finalizers (enabled by default)
+func SetSignalForcedExit(enable bool) {
+	signalsMutex.Lock()
+	defer signalsMutex.Unlock()
+
+	signalForcedExit = enable
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func signalExitCode(sig os.Signal) int {
+	signalsMutex.RLock()
+	defer signalsMutex.RUnlock()
+
+	if code, exists := signalExitCodes[sig]; exists {
+		return code
+	}
+
+	if s, ok := sig.(syscall.Signal); ok && signalNumberCodes {
+		return 128 + int(s)
+	}
+
+	return 0
+}
+
+func signalHandler() {
+	signal.Notify(signalChan, defaultSignals...)
+
+	for sig := range signalChan {
+		processSignal(sig)
+	}
+}
+
+func processSignal(sig os.Signal) {
+	signalsMutex.RLock()
+	handler := signalHandlers[sig]
+	forcedExit := signalForcedExit
+	signalsMutex.RUnlock()
+
+	switch {
+	case handler != nil:
+		logMessage(LogLevelInfo, "Signal \"%s\" received", sig.String())
+		handler(sig)
+
+	case slices.Contains(stopSignals, sig):
+		logMessage(LogLevelInfo, "Signal \"%s\" received", sig.String())
+
+		app := DefaultApp()
+		if sig == os.Interrupt && app.interrupts.Add(1) > 1 && forcedExit {
+			logMessage(LogLevelNotice, "Repeated signal \"%s\". Force killing.", sig.String())
+			app.Kill()
+			return
+		}
+
+		app.StopEx(signalExitCode(sig), "signal "+sig.String())
+
+	default:
+		logMessage(LogLevelDebug, "Signal \"%s\" received", sig.String())
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
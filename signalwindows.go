@@ -4,29 +4,17 @@ package misc
 
 import (
 	"os"
-	"os/signal"
 	"syscall"
 )
 
 //----------------------------------------------------------------------------------------------------------------------------//
 
-func signalHandler() {
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
+var (
+	// signals processed by default
+	defaultSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
 
-	for {
-		signal := <-c
-
-		switch signal {
-		case syscall.SIGINT:
-			fallthrough
-		case syscall.SIGTERM:
-			Logger("", "IN", "Signal \"%s\" received", signal.String())
-			StopApp(0)
-		default:
-			Logger("", "DE", "Signal \"%s\" received", signal.String())
-		}
-	}
-}
+	// signals stopping the application
+	stopSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+)
 
 //----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,347 @@
+package misc
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type (
+	// LifecycleState -- state of the application lifecycle
+	LifecycleState int
+
+	// LifecycleSubscriber -- called on each state transition. Transitions are delivered in order by one goroutine at once
+	// without the lifecycle locks held, so the subscriber may call any App method (Stop, Exit, RegisterComponent, SetReady etc.).
+	// The transitions caused by the subscriber are delivered after it returns, it must not wait for their notifications
+	LifecycleSubscriber func(from LifecycleState, to LifecycleState)
+
+	// Component -- named subsystem reporting its readiness
+	Component struct {
+		app     *App
+		name    string
+		ready   bool
+		message string
+		since   time.Time
+	}
+
+	// ComponentStatus -- readiness of the component
+	ComponentStatus struct {
+		Name    string    `json:"name"`
+		Ready   bool      `json:"ready"`
+		Message string    `json:"message,omitempty"`
+		Since   time.Time `json:"since"`
+	}
+
+	// LifecycleStatus -- snapshot of the application lifecycle, suitable for the health endpoints
+	LifecycleStatus struct {
+		State      LifecycleState    `json:"state"`
+		Since      time.Time         `json:"since"`
+		Live       bool              `json:"live"`
+		Ready      bool              `json:"ready"`
+		Components []ComponentStatus `json:"components"`
+	}
+
+	lifecycle struct {
+		mutex sync.RWMutex
+
+		state       LifecycleState
+		since       time.Time
+		components  []*Component
+		subscribers map[int]LifecycleSubscriber
+		lastSubID   int
+
+		notifications []lifecycleNotification // transitions waiting for the delivery
+		notifying     bool                    // some goroutine delivers the notifications
+	}
+
+	lifecycleNotification struct {
+		from        LifecycleState
+		to          LifecycleState
+		subscribers []LifecycleSubscriber
+	}
+)
+
+// Lifecycle states
+const (
+	StateStarting LifecycleState = iota
+	StateInitializing
+	StateReady
+	StateDraining
+	StateStopping
+	StateStopped
+)
+
+var (
+	lifecycleStateNames = []string{"starting", "initializing", "ready", "draining", "stopping", "stopped"}
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// String --
+func (s LifecycleState) String() string {
+	if s < 0 || int(s) >= len(lifecycleStateNames) {
+		return fmt.Sprintf("?(%d)", s)
+	}
+
+	return lifecycleStateNames[s]
+}
+
+// MarshalText --
+func (s LifecycleState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText --
+func (s *LifecycleState) UnmarshalText(text []byte) error {
+	i := slices.Index(lifecycleStateNames, string(text))
+	if i < 0 {
+		return fmt.Errorf(`unknown lifecycle state "%s"`, text)
+	}
+
+	*s = LifecycleState(i)
+	return nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// State -- current lifecycle state
+func (a *App) State() LifecycleState {
+	a.lifecycle.mutex.RLock()
+	defer a.lifecycle.mutex.RUnlock()
+
+	return a.lifecycle.state
+}
+
+// Status -- snapshot of the lifecycle
+func (a *App) Status() LifecycleStatus {
+	l := &a.lifecycle
+
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	st := LifecycleStatus{
+		State:      l.state,
+		Since:      l.since,
+		Live:       l.state != StateStopped,
+		Ready:      l.state == StateReady,
+		Components: make([]ComponentStatus, len(l.components)),
+	}
+
+	for i, c := range l.components {
+		st.Components[i] = ComponentStatus{
+			Name:    c.name,
+			Ready:   c.ready,
+			Message: c.message,
+			Since:   c.since,
+		}
+	}
+
+	return st
+}
+
+// Subscribe -- subscribe to the state transitions, returns the function cancelling the subscription
+func (a *App) Subscribe(f LifecycleSubscriber) (unsubscribe func()) {
+	l := &a.lifecycle
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.subscribers == nil {
+		l.subscribers = make(map[int]LifecycleSubscriber, 8)
+	}
+
+	l.lastSubID++
+	id := l.lastSubID
+	l.subscribers[id] = f
+
+	return func() {
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+
+		delete(l.subscribers, id)
+	}
+}
+
+// RegisterComponent -- register the component, it is not ready until SetReady(true) is called.
+// The application is ready when it is initialized and all components are ready
+func (a *App) RegisterComponent(name string) *Component {
+	c := &Component{
+		app:   a,
+		name:  name,
+		since: a.clock().Now(),
+	}
+
+	a.updateState(func(l *lifecycle) {
+		l.components = slices.DeleteFunc(l.components, func(c *Component) bool { return c.name == name })
+		l.components = append(l.components, c)
+	})
+
+	return c
+}
+
+// Name --
+func (c *Component) Name() string {
+	return c.name
+}
+
+// SetReady -- report the readiness of the component
+func (c *Component) SetReady(ready bool, message string) {
+	c.app.updateState(func(l *lifecycle) {
+		if c.ready != ready {
+			c.since = c.app.clock().Now()
+		}
+		c.ready = ready
+		c.message = message
+	})
+}
+
+// Unregister -- remove the component
+func (c *Component) Unregister() {
+	c.app.updateState(func(l *lifecycle) {
+		l.components = slices.DeleteFunc(l.components, func(x *Component) bool { return x == c })
+	})
+}
+
+// updateState -- change the data and recalculate the readiness
+func (a *App) updateState(f func(l *lifecycle)) {
+	a.changeState(func(l *lifecycle) LifecycleState {
+		f(l)
+
+		if l.state > StateReady {
+			return l.state
+		}
+
+		if !a.Initialized() {
+			if len(l.components) == 0 {
+				return l.state
+			}
+			return StateInitializing
+		}
+
+		for _, c := range l.components {
+			if !c.ready {
+				return StateInitializing
+			}
+		}
+
+		return StateReady
+	})
+}
+
+// setState -- move to the state, the lifecycle never goes back from draining and later states
+func (a *App) setState(state LifecycleState) {
+	a.changeState(func(l *lifecycle) LifecycleState {
+		if state < l.state && l.state > StateReady {
+			return l.state
+		}
+		return state
+	})
+}
+
+func (a *App) changeState(f func(l *lifecycle) LifecycleState) {
+	l := &a.lifecycle
+
+	l.mutex.Lock()
+
+	from := l.state
+	to := f(l)
+
+	if to == from {
+		l.mutex.Unlock()
+		return
+	}
+
+	l.state = to
+	l.since = a.clock().Now()
+
+	ids := make([]int, 0, len(l.subscribers))
+	for id := range l.subscribers {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	n := lifecycleNotification{
+		from:        from,
+		to:          to,
+		subscribers: make([]LifecycleSubscriber, 0, len(ids)),
+	}
+	for _, id := range ids {
+		n.subscribers = append(n.subscribers, l.subscribers[id])
+	}
+
+	l.notifications = append(l.notifications, n)
+
+	if l.notifying {
+		// it will be delivered by the goroutine delivering the previous ones, possibly the caller's subscriber
+		l.mutex.Unlock()
+		return
+	}
+
+	l.notifying = true
+	l.mutex.Unlock()
+
+	a.notifySubscribers()
+}
+
+// notifySubscribers -- deliver the queued transitions until the queue is empty
+func (a *App) notifySubscribers() {
+	l := &a.lifecycle
+
+	delivered := false
+	defer func() {
+		if !delivered {
+			// the subscriber panicked, the next transition continues the delivery
+			l.mutex.Lock()
+			l.notifying = false
+			l.mutex.Unlock()
+		}
+	}()
+
+	for {
+		l.mutex.Lock()
+		if len(l.notifications) == 0 {
+			l.notifications = nil
+			l.notifying = false
+			l.mutex.Unlock()
+			delivered = true
+			return
+		}
+
+		n := l.notifications[0]
+		l.notifications = l.notifications[1:]
+		l.mutex.Unlock()
+
+		logMessage(LogLevelDebug, "Application state changed from %s to %s", n.from, n.to)
+
+		for _, s := range n.subscribers {
+			s(n.from, n.to)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// AppState -- current lifecycle state
+func AppState() LifecycleState {
+	return DefaultApp().State()
+}
+
+// AppStatus -- snapshot of the lifecycle
+func AppStatus() LifecycleStatus {
+	return DefaultApp().Status()
+}
+
+// SubscribeLifecycle -- subscribe to the state transitions
+func SubscribeLifecycle(f LifecycleSubscriber) (unsubscribe func()) {
+	return DefaultApp().Subscribe(f)
+}
+
+// RegisterComponent --
+func RegisterComponent(name string) *Component {
+	return DefaultApp().RegisterComponent(name)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
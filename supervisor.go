@@ -0,0 +1,243 @@
+package misc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type (
+	// WorkerFunc -- supervised function, it must return when ctx is canceled (the application stops)
+	WorkerFunc func(ctx context.Context) error
+
+	// WorkerOptions --
+	WorkerOptions struct {
+		// Initial restart delay, 0 -- DefaultWorkerMinBackoff. It doubles after each failure up to MaxBackoff
+		MinBackoff time.Duration
+		// Maximal restart delay, 0 -- DefaultWorkerMaxBackoff
+		MaxBackoff time.Duration
+		// Maximal number of restarts, 0 -- unlimited
+		MaxRestarts int
+		// Restart the worker returned without error too
+		RestartOnSuccess bool
+	}
+
+	// WorkerStatus --
+	WorkerStatus struct {
+		Name      string    `json:"name"`
+		Active    bool      `json:"active"`  // the supervision loop is alive: the function is running or waits for the restart
+		Running   bool      `json:"running"` // the function is running
+		Started   time.Time `json:"started"`
+		Restarts  int       `json:"restarts"`
+		Panics    int       `json:"panics"`
+		LastError string    `json:"lastError,omitempty"`
+	}
+
+	worker struct {
+		name   string
+		f      WorkerFunc
+		opts   WorkerOptions
+		mutex  sync.Mutex
+		status WorkerStatus
+	}
+
+	workers struct {
+		mutex  sync.RWMutex
+		list   map[string]*worker
+		panics atomic.Int64
+	}
+)
+
+const (
+	// DefaultWorkerMinBackoff --
+	DefaultWorkerMinBackoff = time.Second
+	// DefaultWorkerMaxBackoff --
+	DefaultWorkerMaxBackoff = time.Minute
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Go -- start the supervised worker. It is restarted with the backoff after the error or the panic
+// and stopped when the application stops. Exit waits for the running workers in the drain phase
+func (a *App) Go(name string, f WorkerFunc, opts *WorkerOptions) error {
+	w := &worker{
+		name: name,
+		f:    f,
+	}
+
+	if opts != nil {
+		w.opts = *opts
+	}
+	if w.opts.MinBackoff <= 0 {
+		w.opts.MinBackoff = DefaultWorkerMinBackoff
+	}
+	if w.opts.MaxBackoff < w.opts.MinBackoff {
+		w.opts.MaxBackoff = max(DefaultWorkerMaxBackoff, w.opts.MinBackoff)
+	}
+
+	w.status.Name = name
+
+	a.workers.mutex.Lock()
+	defer a.workers.mutex.Unlock()
+
+	if old, exists := a.workers.list[name]; exists && old.Status().Active {
+		return fmt.Errorf(`worker "%s" is already active`, name)
+	}
+
+	done, ok := a.BeginWork()
+	if !ok {
+		return errors.New("application is stopped")
+	}
+
+	if a.workers.list == nil {
+		a.workers.list = make(map[string]*worker, 16)
+	}
+	a.workers.list[name] = w
+
+	w.status.Active = true
+	w.status.Running = true
+	go a.runWorker(w, done)
+
+	return nil
+}
+
+// Workers -- status of the workers
+func (a *App) Workers() []WorkerStatus {
+	a.workers.mutex.RLock()
+	defer a.workers.mutex.RUnlock()
+
+	list := make([]WorkerStatus, 0, len(a.workers.list))
+	for _, w := range a.workers.list {
+		list = append(list, w.Status())
+	}
+
+	slices.SortFunc(list, func(a, b WorkerStatus) int { return strings.Compare(a.Name, b.Name) })
+	return list
+}
+
+// WorkerPanics -- total number of the workers panics
+func (a *App) WorkerPanics() int64 {
+	return a.workers.panics.Load()
+}
+
+// Status --
+func (w *worker) Status() WorkerStatus {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.status
+}
+
+func (a *App) runWorker(w *worker, done func()) {
+	defer done()
+
+	defer func() {
+		w.mutex.Lock()
+		w.status.Active = false
+		w.mutex.Unlock()
+	}()
+
+	ctx := a.Context()
+	backoff := w.opts.MinBackoff
+
+	for {
+		t0 := a.clock().Now()
+
+		w.mutex.Lock()
+		w.status.Running = true
+		w.status.Started = t0
+		w.mutex.Unlock()
+
+		err := a.callWorker(ctx, w)
+
+		w.mutex.Lock()
+		w.status.Running = false
+		if err != nil {
+			w.status.LastError = err.Error()
+		}
+		restarts := w.status.Restarts
+		w.mutex.Unlock()
+
+		if ctx.Err() != nil {
+			logMessage(LogLevelDebug, `Worker "%s" stopped`, w.name)
+			return
+		}
+
+		if err == nil && !w.opts.RestartOnSuccess {
+			logMessage(LogLevelDebug, `Worker "%s" finished`, w.name)
+			return
+		}
+
+		if w.opts.MaxRestarts > 0 && restarts >= w.opts.MaxRestarts {
+			logMessage(LogLevelError, `Worker "%s" failed: %v, restarts limit %d reached`, w.name, err, w.opts.MaxRestarts)
+			return
+		}
+
+		if a.clock().Now().Sub(t0) > w.opts.MaxBackoff {
+			// it worked long enough
+			backoff = w.opts.MinBackoff
+		}
+
+		if err != nil {
+			logMessage(LogLevelWarning, `Worker "%s" failed: %v, restart in %s`, w.name, err, backoff)
+		} else {
+			logMessage(LogLevelDebug, `Worker "%s" finished, restart in %s`, w.name, backoff)
+		}
+
+		select {
+		case <-ctx.Done():
+			logMessage(LogLevelDebug, `Worker "%s" stopped`, w.name)
+			return
+		case <-a.clock().After(backoff):
+		}
+
+		w.mutex.Lock()
+		w.status.Restarts++
+		w.mutex.Unlock()
+
+		backoff = min(2*backoff, w.opts.MaxBackoff)
+	}
+}
+
+func (a *App) callWorker(ctx context.Context, w *worker) (err error) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+
+		stack := GetCallStack(0)
+		logMessage(LogLevelError, "Worker \"%s\" panicked: %v\n%s", w.name, r, FormatCallStack(stack))
+
+		a.workers.panics.Add(1)
+
+		w.mutex.Lock()
+		w.status.Panics++
+		w.mutex.Unlock()
+
+		err = fmt.Errorf("panic: %v", r)
+	}()
+
+	return w.f(ctx)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Go -- start the supervised worker, see App.Go
+func Go(name string, f WorkerFunc, opts *WorkerOptions) error {
+	return DefaultApp().Go(name, f, opts)
+}
+
+// Workers --
+func Workers() []WorkerStatus {
+	return DefaultApp().Workers()
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
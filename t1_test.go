@@ -2,9 +2,23 @@ package misc
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
 	"reflect"
 	"runtime"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -680,3 +694,1752 @@ func TestFieldByName(t *testing.T) {
 }
 
 //----------------------------------------------------------------------------------------------------------------------------//
+
+func TestStopCause(t *testing.T) {
+	if AppContext().Err() != nil {
+		t.Fatalf("application context is canceled before stop")
+	}
+
+	if c := AppStopCause(); c != nil {
+		t.Fatalf("got cause %v before stop", c)
+	}
+
+	var err error = &StopCause{Code: ExConfigErrors, Reason: "bad config"}
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("errors.Is(%v, context.Canceled) is false", err)
+	}
+
+	expected := "application stopped with code 78: bad config"
+	if err.Error() != expected {
+		t.Errorf(`got "%s", expected "%s"`, err, expected)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestFinalizers(t *testing.T) {
+	var mutex sync.Mutex
+	var order []string
+
+	f := func(code int, param any) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		order = append(order, param.(string))
+	}
+
+	a := NewApp()
+
+	a.AddFinalizer("s1", f, "s1")
+	a.AddFinalizer("s2", f, "s2")
+	a.AddFinalizer("s3", f, "s3")
+	a.AddFinalizerEx("api", f, "api", FinalizerOptions{DependsOn: []string{"db", "cache"}})
+	a.AddFinalizerEx("db", f, "db", FinalizerOptions{})
+	a.AddFinalizerEx("cache", f, "cache", FinalizerOptions{DependsOn: []string{"db"}})
+	a.AddFinalizerEx("slow", func(code int, param any) { time.Sleep(time.Second) }, nil, FinalizerOptions{Timeout: 10 * time.Millisecond})
+
+	results := a.callFinalizers(0)
+	if len(results) != 7 {
+		t.Fatalf("got %d results, expected %d", len(results), 7)
+	}
+
+	pos := make(map[string]int, len(order))
+	for i, name := range order {
+		pos[name] = i
+	}
+
+	before := [][2]string{
+		{"s3", "s2"},
+		{"s2", "s1"},
+		{"api", "db"},
+		{"api", "cache"},
+		{"cache", "db"},
+	}
+	for _, b := range before {
+		if pos[b[0]] > pos[b[1]] {
+			t.Errorf(`"%s" called after "%s": %v`, b[0], b[1], order)
+		}
+	}
+
+	for _, r := range results {
+		if r.TimedOut != (r.Name == "slow") {
+			t.Errorf(`"%s": TimedOut is %v`, r.Name, r.TimedOut)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestFinalizersCycle(t *testing.T) {
+	list := []exitElement{
+		{name: "a", opts: FinalizerOptions{DependsOn: []string{"b"}}},
+		{name: "b", opts: FinalizerOptions{DependsOn: []string{"a"}}},
+		{name: "c", opts: FinalizerOptions{DependsOn: []string{"b", "unknown"}}},
+	}
+
+	waitFor := finalizersGraph(list)
+
+	expected := [][]int{{1}, {2}, nil}
+	for i, w := range waitFor {
+		if len(w) != len(expected[i]) || (len(w) > 0 && w[0] != expected[i][0]) {
+			t.Errorf(`[%d] got %v, expected %v`, i, w, expected[i])
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestFinalizersPanic(t *testing.T) {
+	called := false
+
+	a := NewApp()
+	a.AddFinalizer("flusher", func(code int, param any) { called = true }, nil)
+	a.AddFinalizer("metrics", func(code int, param any) { panic("metrics failed") }, nil)
+
+	results := a.callFinalizers(0)
+
+	if !called {
+		t.Errorf(`finalizer "flusher" was not called`)
+	}
+
+	if !logFinalizersReport(results) {
+		t.Errorf("panic is not reported")
+	}
+
+	for _, r := range results {
+		if r.Name != "metrics" {
+			continue
+		}
+
+		if !r.Panicked || r.Panic != "metrics failed" || len(r.Stack) == 0 {
+			t.Errorf("got %#v", r)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type (
+	testClock struct {
+		mutex  sync.Mutex
+		now    time.Time
+		timers []testTimer
+	}
+
+	testTimer struct {
+		at time.Time
+		c  chan time.Time
+	}
+)
+
+func (c *testClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.now
+}
+
+func (c *testClock) After(d time.Duration) <-chan time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+
+	c.timers = append(c.timers, testTimer{at: c.now.Add(d), c: ch})
+	return ch
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.now = c.now.Add(d)
+
+	timers := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			timers = append(timers, t)
+			continue
+		}
+		t.c <- c.now
+	}
+	c.timers = timers
+}
+
+func (c *testClock) waitTimers(t *testing.T, n int) {
+	for i := 0; i < 1000; i++ {
+		c.mutex.Lock()
+		ln := len(c.timers)
+		c.mutex.Unlock()
+
+		if ln >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatalf("%d timers expected", n)
+}
+
+func TestAppLifecycle(t *testing.T) {
+	clock := &testClock{now: time.Now()}
+	exits := make(chan int, 2)
+	finalized := make(chan int, 1)
+
+	a := NewApp()
+	a.SetClock(clock)
+	a.SetExitFunc(func(code int) { exits <- code })
+	a.SetExitTimeouts(5*time.Second, 10*time.Second)
+	a.AddFinalizer("f", func(code int, param any) { finalized <- code }, nil)
+
+	a.StopEx(ExServiceError, "test")
+
+	if a.Started() {
+		t.Fatalf("application is started after Stop")
+	}
+
+	if c := a.StopCause(); c == nil || c.Code != ExServiceError || c.Reason != "test" {
+		t.Fatalf("got cause %v", c)
+	}
+
+	if !errors.Is(context.Cause(a.Context()), context.Canceled) {
+		t.Fatalf("context is not canceled")
+	}
+
+	// termination timeout -> Exit
+	clock.waitTimers(t, 1)
+	clock.Advance(5 * time.Second)
+
+	if code := <-finalized; code != ExServiceError {
+		t.Errorf("finalizer got code %d, expected %d", code, ExServiceError)
+	}
+
+	if code := <-exits; code != ExServiceError {
+		t.Errorf("exit with code %d, expected %d", code, ExServiceError)
+	}
+
+	// killing timeout
+	clock.waitTimers(t, 1)
+	clock.Advance(10 * time.Second)
+
+	if code := <-exits; code != ExServiceError {
+		t.Errorf("forced exit with code %d, expected %d", code, ExServiceError)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestSignals(t *testing.T) {
+	received := make(chan os.Signal, 1)
+	HandleSignal(syscall.SIGHUP, func(sig os.Signal) { received <- sig })
+	defer HandleSignal(syscall.SIGHUP, nil)
+
+	processSignal(syscall.SIGHUP)
+	if sig := <-received; sig != syscall.SIGHUP {
+		t.Errorf(`got "%s", expected "%s"`, sig, syscall.SIGHUP)
+	}
+
+	SetSignalNumberExitCodes(true)
+	SetSignalExitCode(syscall.SIGINT, 2)
+	defer func() {
+		SetSignalNumberExitCodes(false)
+		SetSignalExitCode(syscall.SIGINT, 0)
+	}()
+
+	if code := signalExitCode(syscall.SIGTERM); code != 128+int(syscall.SIGTERM) {
+		t.Errorf("got exit code %d for SIGTERM", code)
+	}
+
+	clock := &testClock{now: time.Now()}
+	exits := make(chan int, 1)
+
+	a := NewApp()
+	a.SetClock(clock)
+	a.SetExitFunc(func(code int) { exits <- code })
+
+	prev := SetDefaultApp(a)
+	defer SetDefaultApp(prev)
+
+	processSignal(syscall.SIGINT)
+	if c := a.StopCause(); c == nil || c.Code != 2 {
+		t.Fatalf("got cause %v", c)
+	}
+
+	select {
+	case code := <-exits:
+		t.Fatalf("forced exit with code %d after the first signal", code)
+	default:
+	}
+
+	processSignal(syscall.SIGINT)
+	if code := <-exits; code != 2 {
+		t.Errorf("forced exit with code %d, expected %d", code, 2)
+	}
+
+	// the application stopped by StopApp: the first signal is not the repeated one
+	a = NewApp()
+	a.SetExitFunc(func(code int) { exits <- code })
+	SetDefaultApp(a)
+
+	a.Stop(3)
+	processSignal(syscall.SIGINT)
+
+	select {
+	case code := <-exits:
+		t.Fatalf("forced exit with code %d after the first signal", code)
+	default:
+	}
+
+	processSignal(syscall.SIGINT)
+	if code := <-exits; code != 3 {
+		t.Errorf("forced exit with code %d, expected %d", code, 3)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestDiagnostics(t *testing.T) {
+	a := NewApp()
+	a.AddFinalizer("db-flusher", func(code int, param any) {}, nil)
+
+	var b bytes.Buffer
+	err := a.WriteDiagnostics(&b, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []string{"Reason:       test", "db-flusher", "goroutine ", "TestDiagnostics"} {
+		if !strings.Contains(b.String(), s) {
+			t.Errorf(`"%s" not found in the dump`, s)
+		}
+	}
+
+	a.EnableDiagnostics(DiagnosticsOptions{Dir: t.TempDir()})
+	defer a.DisableDiagnostics()
+
+	fileName, err := a.DumpDiagnostics("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = os.Stat(fileName); err != nil {
+		t.Error(err)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestLogLevels(t *testing.T) {
+	for l := LogLevelEmergency; l <= LogLevelTrace; l++ {
+		l2, ok := ParseLogLevel(l.String())
+		if !ok || l2 != l {
+			t.Errorf(`"%s": got %d, expected %d`, l, l2, l)
+		}
+
+		if l != LogLevelTime && LogLevelFromSlog(l.SlogLevel()) != l {
+			t.Errorf(`"%s": slog level %s gives %s`, l, l.SlogLevel(), LogLevelFromSlog(l.SlogLevel()))
+		}
+	}
+}
+
+func TestSlog(t *testing.T) {
+	var got []string
+
+	prev := SetLogger(func(facility string, level string, message string, params ...any) {
+		got = append(got, facility+" "+level+" "+fmt.Sprintf(message, params...))
+	})
+	defer SetLogger(prev)
+
+	l := slog.New(NewSlogHandler("http", slog.LevelDebug)).With("id", 7).WithGroup("req")
+	l.Warn("slow request", "path", "/a b", "ms", 1500)
+	LogAttrs("db", LogLevelError, "query failed", "table", "users")
+
+	expected := []string{
+		`http WA slow request id=7 req.path="/a b" req.ms=1500`,
+		`db ER query failed table=users`,
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("got %q, expected %q", got, expected)
+	}
+
+	var b bytes.Buffer
+	SetLogger(LoggerFromSlog(slog.New(slog.NewTextHandler(&b, nil))))
+	GetLogger()("db", "NO", "connected to %s", "main")
+
+	if !strings.Contains(b.String(), `level=INFO+2 msg="connected to main" facility=db`) {
+		t.Errorf("got %s", b.String())
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestLifecycleState(t *testing.T) {
+	clock := &testClock{now: time.Now()}
+	exits := make(chan int, 2)
+
+	a := NewApp()
+	a.SetClock(clock)
+	a.SetExitFunc(func(code int) { exits <- code })
+
+	var mutex sync.Mutex
+	var transitions []string
+	unsubscribe := a.Subscribe(func(from LifecycleState, to LifecycleState) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		transitions = append(transitions, from.String()+"->"+to.String())
+	})
+	defer unsubscribe()
+
+	db := a.RegisterComponent("db")
+	http := a.RegisterComponent("http")
+
+	a.SetInitialized()
+	db.SetReady(true, "")
+	if a.State() != StateInitializing {
+		t.Fatalf(`got state "%s", expected "%s"`, a.State(), StateInitializing)
+	}
+
+	http.SetReady(true, "listening")
+	st := a.Status()
+	if st.State != StateReady || !st.Ready || !st.Live || len(st.Components) != 2 {
+		t.Fatalf("got %#v", st)
+	}
+
+	db.SetReady(false, "connection lost")
+	db.SetReady(true, "")
+
+	a.Stop(0)
+	http.SetReady(false, "closed")
+	if a.State() != StateDraining {
+		t.Fatalf(`got state "%s", expected "%s"`, a.State(), StateDraining)
+	}
+
+	go a.Exit()
+	<-exits
+
+	expected := []string{
+		"starting->initializing",
+		"initializing->ready",
+		"ready->initializing",
+		"initializing->ready",
+		"ready->draining",
+		"draining->stopping",
+		"stopping->stopped",
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if !reflect.DeepEqual(transitions, expected) {
+		t.Errorf("got %v, expected %v", transitions, expected)
+	}
+}
+
+func TestLifecycleSubscriberCalls(t *testing.T) {
+	clock := &testClock{now: time.Now()}
+
+	a := NewApp()
+	a.SetClock(clock)
+	a.SetExitFunc(func(code int) {})
+
+	db := a.RegisterComponent("db")
+
+	var transitions []string
+	unsubscribe := a.Subscribe(func(from LifecycleState, to LifecycleState) {
+		transitions = append(transitions, from.String()+"->"+to.String())
+
+		switch to {
+		case StateReady:
+			a.RegisterComponent("cache").SetReady(true, "")
+			a.Stop(0)
+		case StateDraining:
+			db.SetReady(false, "closed")
+		}
+	})
+	defer unsubscribe()
+
+	done := make(chan struct{})
+	go func() {
+		a.SetInitialized()
+		db.SetReady(true, "")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("subscriber calls deadlocked")
+	}
+
+	// the transitions caused by the subscriber are delivered after it returns, in order
+	expected := []string{
+		"initializing->ready",
+		"ready->initializing",
+		"initializing->ready",
+		"ready->draining",
+	}
+
+	if !reflect.DeepEqual(transitions, expected) {
+		t.Errorf("got %v, expected %v", transitions, expected)
+	}
+
+	st := a.Status()
+	if st.State != StateDraining || len(st.Components) != 2 || st.Components[0].Ready {
+		t.Errorf("got %#v", st)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestDrain(t *testing.T) {
+	clock := &testClock{now: time.Now()}
+	exits := make(chan int, 1)
+
+	a := NewApp()
+	a.SetClock(clock)
+	a.SetExitFunc(func(code int) { exits <- code })
+	a.SetDrainTimeout(10 * time.Second)
+
+	done1, ok1 := a.BeginWork()
+	done2, ok2 := a.BeginWork()
+	if !ok1 || !ok2 || a.InFlight() != 2 {
+		t.Fatalf("got %v %v %d", ok1, ok2, a.InFlight())
+	}
+
+	a.Stop(0)
+
+	if _, ok := a.BeginWork(); ok || a.InFlight() != 2 {
+		t.Fatalf("work is accepted after stop: %v %d", ok, a.InFlight())
+	}
+
+	go a.Exit()
+
+	// killer, drain deadline and progress
+	clock.waitTimers(t, 3)
+
+	done1()
+	done1()
+	if a.InFlight() != 1 {
+		t.Fatalf("got %d in-flight, expected 1", a.InFlight())
+	}
+
+	select {
+	case <-exits:
+		t.Fatalf("exit before the work is done")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	done2()
+	<-exits
+
+	if a.State() != StateStopped {
+		t.Errorf(`got state "%s", expected "%s"`, a.State(), StateStopped)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestRestart(t *testing.T) {
+	if os.Getenv("MISC_TEST_RESTART_CHILD") != "" {
+		// child process
+		if !IsRestarted() || os.Getenv(EnvRestartReadyFD) != "" || os.Getenv(EnvRestartFiles) != "" {
+			os.Exit(5)
+		}
+
+		l, err := InheritedListener("test")
+		if err != nil || l == nil {
+			os.Exit(2)
+		}
+
+		// taken only once
+		if l, err := InheritedListener("test"); err != nil || l != nil {
+			os.Exit(7)
+		}
+
+		if _, err := CreatePidFile(os.Getenv("MISC_TEST_RESTART_PIDFILE")); err != nil {
+			os.Exit(6)
+		}
+
+		if NotifyRestartReady() != nil {
+			os.Exit(3)
+		}
+
+		conn, err := l.Accept()
+		if err != nil {
+			os.Exit(4)
+		}
+		conn.Write([]byte("child"))
+		conn.Close()
+		os.Exit(0)
+	}
+
+	if runtime.GOOS == "windows" {
+		t.Skip("not supported")
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	pidName := filepath.Join(t.TempDir(), "restart.pid")
+	pid, err := CreatePidFile(pidName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pid.Remove()
+
+	a := NewApp()
+	a.SetExitFunc(func(code int) {})
+
+	err = a.Restart(
+		RestartOptions{
+			Listeners:    map[string]net.Listener{"test": l},
+			Args:         []string{"-test.run=^TestRestart$"},
+			Env:          []string{"MISC_TEST_RESTART_CHILD=1", "MISC_TEST_RESTART_PIDFILE=" + pidName},
+			ReadyTimeout: 10 * time.Second,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a.Started() {
+		t.Errorf("parent is not stopped after restart")
+	}
+
+	// the child took over the PID file, the parent finalizer must not remove it
+	if n, _ := ReadPidFile(pidName); n == os.Getpid() || n == 0 {
+		t.Errorf("got pid %d in the PID file", n)
+	}
+
+	pid.Remove()
+	if _, err := os.Stat(pidName); err != nil {
+		t.Errorf("handed over PID file: %s", err)
+	}
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	b, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != "child" {
+		t.Errorf(`got "%s", expected "%s"`, b, "child")
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestSupervisor(t *testing.T) {
+	a := NewApp()
+	a.SetExitFunc(func(code int) {})
+
+	var calls atomic.Int32
+	var finished atomic.Bool
+
+	err := a.Go("w",
+		func(ctx context.Context) error {
+			switch calls.Add(1) {
+			case 1:
+				panic("boom")
+			case 2:
+				return errors.New("fail")
+			}
+
+			<-ctx.Done()
+			time.Sleep(20 * time.Millisecond)
+			finished.Store(true)
+			return nil
+		},
+		&WorkerOptions{MinBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a.Go("w", func(ctx context.Context) error { return nil }, nil) == nil {
+		t.Fatalf("duplicate worker is started")
+	}
+
+	for i := 0; calls.Load() < 3; i++ {
+		if i == 1000 {
+			t.Fatalf("worker is not restarted")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	st := a.Workers()
+	if len(st) != 1 || !st[0].Running || st[0].Restarts != 2 || st[0].Panics != 1 || st[0].LastError != "fail" || a.WorkerPanics() != 1 {
+		t.Fatalf("got %#v", st)
+	}
+
+	a.Exit()
+
+	if !finished.Load() {
+		t.Errorf("Exit did not wait for the worker")
+	}
+}
+
+func TestSupervisorDuplicate(t *testing.T) {
+	a := NewApp()
+	a.SetExitFunc(func(code int) {})
+	defer a.Exit()
+
+	var calls atomic.Int32
+	f := func(ctx context.Context) error {
+		if calls.Add(1) == 1 {
+			return errors.New("fail")
+		}
+		return nil
+	}
+
+	if err := a.Go("w", f, &WorkerOptions{MinBackoff: 200 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+
+	waitStatus := func(cond func(st WorkerStatus) bool) {
+		for i := 0; ; i++ {
+			if st := a.Workers(); len(st) == 1 && cond(st[0]) {
+				return
+			}
+			if i == 2000 {
+				t.Fatalf("got %#v", a.Workers())
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// waiting for the restart
+	waitStatus(func(st WorkerStatus) bool { return !st.Running && st.Active && st.LastError != "" })
+
+	if a.Go("w", f, nil) == nil {
+		t.Fatalf("duplicate worker is started during the backoff")
+	}
+
+	waitStatus(func(st WorkerStatus) bool { return !st.Active })
+
+	if err := a.Go("w", func(ctx context.Context) error { return nil }, nil); err != nil {
+		t.Errorf("worker is not restarted after the loop exit: %s", err)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestCron(t *testing.T) {
+	from := time.Date(2026, 1, 30, 10, 15, 30, 0, time.UTC)
+
+	cases := []struct {
+		spec     string
+		isError  bool
+		expected time.Time
+	}{
+		{"* * * * *", false, time.Date(2026, 1, 30, 10, 16, 0, 0, time.UTC)},
+		{"*/10 * * * * *", false, time.Date(2026, 1, 30, 10, 15, 40, 0, time.UTC)},
+		{"0 9-17/4 * * mon-fri", false, time.Date(2026, 1, 30, 13, 0, 0, 0, time.UTC)},
+		{"30 2 * * sun", false, time.Date(2026, 2, 1, 2, 30, 0, 0, time.UTC)},
+		{"0 0 31 * *", false, time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 * *", false, time.Date(2026, 3, 30, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", false, time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * 5", false, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * 5", false, time.Date(2026, 2, 6, 0, 0, 0, 0, time.UTC)},
+		{"@daily", false, time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", false, time.Time{}},
+		{"1h30m", false, from.Add(90 * time.Minute)},
+		{"@every 1d", false, from.Add(24 * time.Hour)},
+		{"* * *", true, time.Time{}},
+		{"60 * * * *", true, time.Time{}},
+		{"5-1 * * * *", true, time.Time{}},
+		{"*/0 * * * *", true, time.Time{}},
+		{"0 0 * xyz *", true, time.Time{}},
+	}
+
+	for i, c := range cases {
+		s, err := ParseJobSchedule(c.spec)
+		if c.isError {
+			if err == nil {
+				t.Errorf(`[%d] "%s": error expected`, i, c.spec)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf(`[%d] "%s": %s`, i, c.spec, err)
+			continue
+		}
+
+		next := s.Next(from)
+		if !next.Equal(c.expected) {
+			t.Errorf(`[%d] "%s": got %v, expected %v`, i, c.spec, next, c.expected)
+		}
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	a := NewApp()
+	a.SetExitFunc(func(code int) {})
+
+	runs := make(chan struct{}, 100)
+
+	err := a.ScheduleJob("tick", "10ms",
+		func(ctx context.Context) error {
+			runs <- struct{}{}
+			return errors.New("tick failed")
+		},
+		&JobOptions{Jitter: time.Millisecond},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a.ScheduleJob("bad", "* * *", nil, nil) == nil {
+		t.Fatalf("error expected")
+	}
+
+	<-runs
+	<-runs
+
+	jobs := a.Jobs()
+	if len(jobs) != 1 || jobs[0].Runs < 1 || jobs[0].LastError != "tick failed" || jobs[0].Schedule != "@every 10ms" || jobs[0].Next.IsZero() {
+		t.Fatalf("got %#v", jobs)
+	}
+
+	if !a.UnscheduleJob("tick") || len(a.Jobs()) != 0 {
+		t.Fatalf("job is not unscheduled")
+	}
+
+	a.Exit()
+
+	n := len(runs)
+	time.Sleep(30 * time.Millisecond)
+	if len(runs) != n {
+		t.Errorf("job is running after exit")
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestPidFile(t *testing.T) {
+	name := t.TempDir() + "/test.pid"
+
+	// stale
+	err := os.WriteFile(name, []byte("999999999\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := CreatePidFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pid, err := ReadPidFile(name)
+	if err != nil || pid != os.Getpid() {
+		t.Fatalf("got %d (%v), expected %d", pid, err, os.Getpid())
+	}
+
+	if runtime.GOOS != "windows" {
+		_, err = CreatePidFile(name)
+		var e *Error
+		if !errors.As(err, &e) || e.Code() != ExAlreadyRunning {
+			t.Fatalf("got %v, expected already running error", err)
+		}
+	}
+
+	if runtime.GOOS != "windows" {
+		// opened by another instance before the removal
+		old, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer old.Close()
+
+		err = p.Remove()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the lock on the removed file can be taken, it must be recognized as the dead one
+		if lockFile(old) != nil || isSameFile(old, name) {
+			t.Errorf("removed file is the pid file")
+		}
+
+		p, err = CreatePidFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isSameFile(old, name) || !isSameFile(p.file(), name) {
+			t.Errorf("recreated file is not recognized")
+		}
+	}
+
+	err = p.Remove()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = os.Stat(name); !os.IsNotExist(err) {
+		t.Errorf("pid file is not removed")
+	}
+
+	if slices.Contains(FinalizerNames(), "pidfile:"+name) {
+		t.Errorf("finalizer is not removed")
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestBuildInfo(t *testing.T) {
+	b := GetBuildInfo()
+
+	if b.GoVersion == "" || b.Version == "" || b.AppName != AppName() {
+		t.Fatalf("got %#v", b)
+	}
+
+	if !strings.HasPrefix(b.String(), b.AppName+" "+b.Version+EOS) {
+		t.Errorf("got %s", b.String())
+	}
+
+	j, err := b.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b2 BuildInfo
+	err = json.Unmarshal(j, &b2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	j2, _ := b2.JSON()
+	if !bytes.Equal(j, j2) {
+		t.Errorf("got %s, expected %s", j2, j)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestSemver(t *testing.T) {
+	order := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"v1.0.1+build.5",
+		"1.2",
+		"2.0.0",
+		"10.0.0",
+	}
+
+	for i := 1; i < len(order); i++ {
+		c, err := CompareVersions(order[i-1], order[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c >= 0 {
+			t.Errorf(`"%s" >= "%s"`, order[i-1], order[i])
+		}
+	}
+
+	if c, _ := CompareVersions("1.0.0+a", "1.0.0+b"); c != 0 {
+		t.Errorf("build metadata is not ignored")
+	}
+
+	for _, s := range []string{"", "1.", "01.2.3", "1.2.3.4", "1.2.3-", "1.2.3-01", "1.2.3+a..b", "x.y.z"} {
+		if _, err := ParseVersion(s); err == nil {
+			t.Errorf(`"%s": error expected`, s)
+		}
+	}
+
+	if s := MustParseVersion("v1.2.3-rc.1+sha.abc").String(); s != "1.2.3-rc.1+sha.abc" {
+		t.Errorf(`got "%s"`, s)
+	}
+
+	cases := []struct {
+		constraint string
+		version    string
+		expected   bool
+	}{
+		{">=1.4, <2", "1.4.0", true},
+		{">=1.4, <2", "1.9.9", true},
+		{">=1.4, <2", "2.0.0", false},
+		{">=1.4, <2", "1.3.9", false},
+		{"~1.2.3", "1.2.9", true},
+		{"~1.2.3", "1.3.0", false},
+		{"^1.2.3", "1.9.0", true},
+		{"^1.2.3", "2.0.0", false},
+		{"^0.2.3", "0.2.9", true},
+		{"^0.2.3", "0.3.0", false},
+		{"1.2.3", "1.2.3", true},
+		{"!=1.2.3", "1.2.3", false},
+		{"<1 || >=3", "3.1.0", true},
+		{"<1 || >=3", "2.1.0", false},
+	}
+
+	for i, c := range cases {
+		vc, err := ParseVersionConstraint(c.constraint)
+		if err != nil {
+			t.Errorf(`[%d] %s`, i, err)
+			continue
+		}
+
+		if vc.Check(MustParseVersion(c.version)) != c.expected {
+			t.Errorf(`[%d] "%s" for "%s": expected %v`, i, c.constraint, c.version, c.expected)
+		}
+	}
+
+	if _, err := ParseVersionConstraint(">=1.4,"); err == nil {
+		t.Errorf("error expected")
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestExitStatus(t *testing.T) {
+	s := ExitStatus(ExConfigErrors).String()
+	if s != "78 (ExIncorrectConfigFile|ExConfigIncorrect|ExConfigErrors, EX_CONFIG)" {
+		t.Errorf(`got "%s"`, s)
+	}
+
+	if s = ExitStatus(200).String(); s != "200" {
+		t.Errorf(`got "%s"`, s)
+	}
+
+	if s = ExitStatus(ExConfigErrors).NamedString("ExConfigErrors"); s != "78 (ExConfigErrors, EX_CONFIG)" {
+		t.Errorf(`got "%s"`, s)
+	}
+
+	if s = ExitStatus(ExConfigErrors).NamedString("ExPanic"); s != ExitStatus(ExConfigErrors).String() {
+		t.Errorf(`got "%s"`, s)
+	}
+
+	if info := ExitStatus(74).Info(); info.Category != "EX_IOERR" || info.Description == "" || len(info.Names) != 0 {
+		t.Errorf("got %#v", info)
+	}
+
+	code, err := RegisterExitStatus(90, "ExTestQueueFull", "queue is full")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c, ok := ExitStatusByName("ExTestQueueFull"); !ok || c != code || code.Name() != "ExTestQueueFull" {
+		t.Errorf("got %d %v", c, ok)
+	}
+
+	if _, err = RegisterExitStatus(91, "ExTestQueueFull", ""); err == nil {
+		t.Errorf("error expected")
+	}
+
+	var mutex sync.Mutex
+	var messages []string
+	prev := SetLogger(func(facility string, level string, message string, params ...any) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		messages = append(messages, fmt.Sprintf(message, params...))
+	})
+	defer SetLogger(prev)
+
+	SetVerbosity("", LogLevelDebug)
+	defer ResetVerbosity("")
+
+	a := NewApp()
+	a.SetExitFunc(func(code int) {})
+
+	if err = a.StopStatus("ExTestUnknown", ""); err == nil || !a.Started() {
+		t.Errorf("error expected")
+	}
+
+	if err = a.StopStatus("ExConfigErrors", "bad config"); err != nil {
+		t.Fatal(err)
+	}
+
+	if c := a.StopCause(); c == nil || c.Code != ExConfigErrors || c.Name != "ExConfigErrors" || c.Reason != "bad config" {
+		t.Errorf("got cause %#v", c)
+	}
+
+	a.Exit()
+
+	mutex.Lock()
+	for _, m := range []string{
+		"Set application exit code 78 (ExConfigErrors, EX_CONFIG): bad config",
+		"Application finished with code 78 (ExConfigErrors, EX_CONFIG)",
+	} {
+		if !slices.Contains(messages, m) {
+			t.Errorf(`"%s" is not logged`, m)
+		}
+	}
+	mutex.Unlock()
+
+	RegisterExitStatus(92, "ExTestServiceError", "service error")
+	RegisterExitStatus(92, "ExTestError", "error")
+	RegisterExitStatus(92, "ExTestServiceFailure", "service error")
+	if d := ExitStatus(92).Info().Description; d != "service error / error" {
+		t.Errorf(`got "%s"`, d)
+	}
+
+	if !slices.ContainsFunc(ExitStatuses(), func(i ExitStatusInfo) bool { return i.Code == 90 }) {
+		t.Errorf("registered code not found")
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestErrorWrap(t *testing.T) {
+	cause := os.ErrNotExist
+	err := WrapError(cause, ExMissingConfigFile, "config %s", "app.conf").With("path", "/etc/app.conf")
+
+	if s := err.Error(); s != "config app.conf: file does not exist" {
+		t.Errorf(`got "%s"`, s)
+	}
+
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("cause is not found")
+	}
+
+	wrapped := fmt.Errorf("start: %w", err)
+
+	if !errors.Is(wrapped, ErrorWithCode(ExMissingConfigFile)) || errors.Is(wrapped, ErrorWithCode(ExConfigErrors)) {
+		t.Errorf("code match failed")
+	}
+
+	var e *Error
+	if !errors.As(wrapped, &e) || e.Code() != ExMissingConfigFile || e.Message() != "config app.conf" {
+		t.Errorf("got %#v", e)
+	}
+
+	if code, ok := ErrorCode(wrapped); !ok || code != ExMissingConfigFile {
+		t.Errorf("got %d %v", code, ok)
+	}
+
+	if st := err.Stack(); len(st) == 0 || !strings.HasSuffix(st[0].FuncName, "TestErrorWrap") {
+		t.Errorf("got %#v", st)
+	}
+
+	if WrapError(nil, ExServiceError, "") != nil {
+		t.Errorf("nil expected")
+	}
+
+	j, e2 := json.Marshal(err)
+	if e2 != nil {
+		t.Fatal(e2)
+	}
+
+	var v map[string]any
+	if e2 = json.Unmarshal(j, &v); e2 != nil {
+		t.Fatal(e2)
+	}
+
+	if v["code"] != float64(ExMissingConfigFile) || v["message"] != err.Error() || v["attrs"].(map[string]any)["path"] != "/etc/app.conf" {
+		t.Errorf("got %s", j)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestCanonicalCodes(t *testing.T) {
+	for c := CodeOK; c <= CodeUnauthenticated; c++ {
+		p, err := ParseCanonicalCode(c.String())
+		if err != nil || p != c {
+			t.Errorf("%d: got %d, %v", c, p, err)
+		}
+	}
+
+	if c := CanonicalFromHTTP(404); c != CodeNotFound || c.HTTPStatus() != 404 {
+		t.Errorf("got %s", c)
+	}
+
+	if c := CanonicalFromHTTP(418); c != CodeFailedPrecondition {
+		t.Errorf("got %s", c)
+	}
+
+	if c := CanonicalFromExitStatus(ExConfigErrors); c != CodeFailedPrecondition || c.ExitStatus() != ExConfigErrors {
+		t.Errorf("got %s", c)
+	}
+
+	err := MakeCanonicalError(CodeNotFound, "user %d", 42).With("id", 42)
+	if err.Code() != 66 || err.HTTPStatus() != 404 {
+		t.Errorf("got %d %d", err.Code(), err.HTTPStatus())
+	}
+
+	if c := CanonicalCodeOf(fmt.Errorf("load: %w", MakeError(ExAccessDenied, "denied"))); c != CodePermissionDenied {
+		t.Errorf("got %s", c)
+	}
+
+	if c := CanonicalCodeOf(context.DeadlineExceeded); c != CodeDeadlineExceeded {
+		t.Errorf("got %s", c)
+	}
+
+	j, e := ProblemJSON(err)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	var v map[string]any
+	if e = json.Unmarshal(j, &v); e != nil {
+		t.Fatal(e)
+	}
+
+	if v["status"] != float64(404) || v["title"] != "Not Found" || v["detail"] != "user 42" || v["code"] != "NotFound" || v["id"] != float64(42) {
+		t.Errorf("got %s", j)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestMessages(t *testing.T) {
+	msgs := NewMessages()
+	defer msgs.Free()
+
+	msgs.Add("bad value %d", 1)
+	msgs.AddError(MakeError(ExConfigErrors, "bad config"))
+	msgs.AddError(fmt.Errorf("open: %w", os.ErrNotExist))
+	msgs.AddWarning("deprecated field %s", "x")
+	msgs.AddError(nil)
+
+	if msgs.Len() != 3 || msgs.Count(MessageSeverityWarning) != 1 {
+		t.Errorf("got %d, %d", msgs.Len(), msgs.Count(MessageSeverityWarning))
+	}
+
+	if s := msgs.String(); s != "bad value 1; bad config; open: file does not exist" {
+		t.Errorf(`got "%s"`, s)
+	}
+
+	if c := msgs.Content(); !reflect.DeepEqual(c, []string{"bad value 1", "bad config", "open: file does not exist"}) {
+		t.Errorf("got %#v", c)
+	}
+
+	err := msgs.Error("\n")
+	if err == nil || !errors.Is(err, os.ErrNotExist) || !errors.Is(err, ErrorWithCode(ExConfigErrors)) {
+		t.Fatalf("got %v", err)
+	}
+
+	var e *Error
+	if !errors.As(err, &e) || e.Code() != ExConfigErrors {
+		t.Errorf("got %v", e)
+	}
+
+	if w := msgs.Warning(); w == nil || w.Error() != "deprecated field x" {
+		t.Errorf("got %v", w)
+	}
+
+	msgs2 := NewMessages()
+	defer msgs2.Free()
+
+	msgs2.AddWarning("only warning")
+	if msgs2.Error() != nil || msgs2.String() != "" || !reflect.DeepEqual(msgs2.Warnings(), []string{"only warning"}) {
+		t.Errorf("warnings must not fail")
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestMessagesSafety(t *testing.T) {
+	msgs := NewMessages()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				msgs.Add("message %d", j)
+				_ = msgs.Content()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if msgs.Len() != 400 {
+		t.Errorf("got %d", msgs.Len())
+	}
+
+	n := 0
+	for sev, err := range msgs.All() {
+		if sev != MessageSeverityError || err == nil {
+			t.Fatalf("got %v, %v", sev, err)
+		}
+		// the iterator works with the snapshot, adding doesn't block or change it
+		msgs.AddWarning("added while iterating")
+		n++
+	}
+
+	if n != 400 || msgs.Count(MessageSeverityWarning) != 400 {
+		t.Errorf("got %d, %d", n, msgs.Count(MessageSeverityWarning))
+	}
+
+	c := msgs.Content()
+	c[0] = "changed"
+	if msgs.Content()[0] == "changed" {
+		t.Errorf("content is not a copy")
+	}
+
+	msgs.Free()
+
+	SetDebugMode(true)
+	defer SetDebugMode(false)
+
+	msgs = NewMessages()
+	msgs.Add("x")
+	msgs.Free()
+
+	for name, f := range map[string]func(){
+		"Add":   func() { msgs.Add("y") },
+		"Len":   func() { msgs.Len() },
+		"Error": func() { _ = msgs.Error() },
+		"Free":  func() { msgs.Free() },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: use after Free is not detected", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestPathRoots(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("MISC_TEST_DIR", tmp)
+	t.Setenv("MISC_TEST_NAME", "name")
+	t.Setenv("XDG_DATA_HOME", filepath.Join(tmp, "xdg"))
+
+	if err := SetPathRoot("test_logs", "{test_base}/logs"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetPathRoot("test_base", "${MISC_TEST_DIR}/base"); err != nil {
+		t.Fatal(err)
+	}
+
+	home, _ := os.UserHomeDir()
+	cwd, _ := os.Getwd()
+
+	for _, df := range []struct {
+		name     string
+		base     string
+		expected string
+	}{
+		{"{test_logs}/app.log", "", filepath.Join(tmp, "base", "logs", "app.log")},
+		{"{test_base}", "", filepath.Join(tmp, "base")},
+		{"{tmp}/x", "", filepath.Join(os.TempDir(), "x")},
+		{"{data}", "", filepath.Join(tmp, "xdg", AppName())},
+		{"{xdg_data}/other", "", filepath.Join(tmp, "xdg", "other")},
+		{"~/x", "", filepath.Join(home, "x")},
+		{"${MISC_TEST_DIR}/a", "", filepath.Join(tmp, "a")},
+		{"^${MISC_TEST_NAME}", tmp, filepath.Join(tmp, "name")},
+		{"^data/$Recycle.Bin/$1@x", tmp, filepath.Join(tmp, "data", "$Recycle.Bin", "$1@x")},
+		{"$x", "", filepath.Join(cwd, "x")},
+		{"^x", tmp, filepath.Join(tmp, "x")},
+		{"@x", "", filepath.Join(AppWorkDir(), "x")},
+		{"x", "", filepath.Join(AppExecPath(), "x")},
+	} {
+		p, err := AbsPathEx(df.name, df.base)
+		if err != nil {
+			t.Errorf(`"%s": %s`, df.name, err)
+			continue
+		}
+		if p != df.expected {
+			t.Errorf(`"%s": got "%s", expected "%s"`, df.name, p, df.expected)
+		}
+	}
+
+	for _, name := range []string{"{unknown}/x", "{tmp", "{tmp}x", "${MISC_TEST_UNDEFINED}/x", "^${MISC_TEST_NAME"} {
+		if p, err := AbsPath(name); err == nil {
+			t.Errorf(`"%s": error expected, got "%s"`, name, p)
+		}
+	}
+
+	SetPathRoot("test_loop", "{test_loop}/x")
+	if _, err := PathRoot("test_loop"); err == nil {
+		t.Errorf("nesting error expected")
+	}
+
+	if err := SetPathRoot("bad/name", "/"); err == nil {
+		t.Errorf("error expected")
+	}
+
+	if !slices.Contains(PathRoots(), "config") {
+		t.Errorf("built-in roots not found")
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestPathResolver(t *testing.T) {
+	tmp := t.TempDir()
+	root := filepath.Join(tmp, "root")
+	outside := filepath.Join(tmp, "outside")
+
+	for _, d := range []string{filepath.Join(root, "sub"), outside} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	os.WriteFile(filepath.Join(root, "sub", "file.txt"), []byte("inside"), 0644)
+	os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644)
+	os.Symlink(outside, filepath.Join(root, "out"))
+	os.Symlink("sub", filepath.Join(root, "in"))
+	os.Symlink(filepath.Join(tmp, "missing"), filepath.Join(root, "dangling"))
+
+	r, err := NewPathResolver(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"sub/file.txt", "^sub/../sub/file.txt", "in/file.txt", "new/file.txt", "", root + "/sub"} {
+		if _, err := r.Resolve(name); err != nil {
+			t.Errorf(`"%s": %s`, name, err)
+		}
+	}
+
+	for _, df := range []struct {
+		name    string
+		symlink bool
+	}{
+		{"../outside/secret.txt", false},
+		{"^../../etc/passwd", false},
+		{"/etc/passwd", false},
+		{"sub/../../outside", false},
+		{"out/secret.txt", true},
+		{"out/new.txt", true},
+		{"dangling/x", true},
+	} {
+		_, err := r.Resolve(df.name)
+
+		var e *PathEscapeError
+		if !errors.Is(err, ErrPathEscape) || !errors.As(err, &e) || e.Symlink != df.symlink {
+			t.Errorf(`"%s": got %v`, df.name, err)
+		}
+	}
+
+	// untrusted names are not expanded
+	t.Setenv("MISC_TEST_SECRET", "s3cr3t")
+	t.Setenv("MISC_TEST_UP", "../../..")
+
+	for _, df := range []struct {
+		name     string
+		expected string
+	}{
+		{"${MISC_TEST_SECRET}.txt", "${MISC_TEST_SECRET}.txt"},
+		{"${MISC_TEST_UP}/etc/passwd", "${MISC_TEST_UP}/etc/passwd"},
+		{"sub/${MISC_TEST_UP}/x", "sub/${MISC_TEST_UP}/x"},
+		{"^${MISC_TEST_UNDEFINED}", "${MISC_TEST_UNDEFINED}"},
+	} {
+		rel, err := r.Rel(df.name)
+		if err != nil || rel != filepath.FromSlash(df.expected) {
+			t.Errorf(`"%s": got "%s", %v`, df.name, rel, err)
+		}
+	}
+
+	if _, err := r.Resolve("../${MISC_TEST_SECRET}"); !errors.Is(err, ErrPathEscape) || strings.Contains(err.Error(), "s3cr3t") {
+		t.Errorf("got %v", err)
+	}
+
+	if rel, err := r.Rel("in/../sub/file.txt"); err != nil || rel != filepath.Join("sub", "file.txt") {
+		t.Errorf(`got "%s", %v`, rel, err)
+	}
+
+	fd, err := r.Open("in/file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(fd)
+	fd.Close()
+
+	if string(data) != "inside" {
+		t.Errorf(`got "%s"`, data)
+	}
+
+	if _, err := r.Open("out/secret.txt"); err == nil {
+		t.Errorf("error expected")
+	}
+
+	if _, err := NewPathResolver(filepath.Join(root, "sub", "file.txt")); err == nil {
+		t.Errorf("error expected")
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestVerbosity(t *testing.T) {
+	defer SetVerbositySpec("")
+	defer SetDebugMode(false)
+
+	if Verbosity("db") != LogLevelInfo || IsDebugFor("db") {
+		t.Fatalf("got %s", Verbosity("db"))
+	}
+
+	// the Logger filtering by the verbosity must not deadlock
+	prevLogger := GetLogger()
+	SetLogger(func(facility string, level string, message string, params ...any) {
+		l, _ := ParseLogLevel(level)
+		if IsLogEnabled(facility, l) {
+			prevLogger(facility, level, message, params...)
+		}
+	})
+	defer SetLogger(prevLogger)
+
+	done := make(chan struct{})
+	go func() {
+		SetVerbosity("db", LogLevelDebug)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("SetVerbosity deadlocked")
+	}
+
+	if !IsDebugFor("db.pool") || IsDebugFor("http") || IsDebugFor("dbx") {
+		t.Errorf("hierarchy failed")
+	}
+
+	// the package logging follows the facility verbosity
+	var mutex sync.Mutex
+	var written []string
+	SetLogger(func(facility string, level string, message string, params ...any) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		written = append(written, facility+" "+level)
+	})
+
+	LogAttrs("db", LogLevelDebug, "query")
+	LogAttrs("http", LogLevelDebug, "request")
+	slog.New(NewSlogHandler("db.pool", slog.LevelDebug)).Debug("connection")
+	slog.New(NewSlogHandler("http", slog.LevelDebug)).Debug("connection")
+	LogProcessingTime("db", "DE", 0, "", "", NowUnixNano())
+	LogProcessingTime("http", "DE", 0, "", "", NowUnixNano())
+	logMessage(LogLevelDebug, "internal")
+
+	SetLogger(prevLogger)
+
+	mutex.Lock()
+	if !reflect.DeepEqual(written, []string{"db DE", "db.pool DE", "db DE"}) {
+		t.Errorf("got %v", written)
+	}
+	mutex.Unlock()
+
+	SetDebugMode(true)
+	if !IsDebugFor("http") {
+		t.Errorf("debug mode default failed")
+	}
+	SetDebugMode(false)
+
+	if err := SetVerbositySpec("WA, http=TR, db.pool=ER"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]LogLevel{"*": LogLevelWarning, "http": LogLevelTrace, "db.pool": LogLevelError}
+	if l := VerbosityLevels(); !reflect.DeepEqual(l, expected) {
+		t.Errorf("got %v", l)
+	}
+
+	if IsLogEnabled("db", LogLevelInfo) || !IsLogEnabled("http.client", LogLevelTrace) || IsLogEnabled("db.pool", LogLevelWarning) {
+		t.Errorf("levels failed")
+	}
+
+	if err := SetVerbositySpec("db=XX"); err == nil {
+		t.Errorf("error expected")
+	}
+
+	envFile := filepath.Join(t.TempDir(), "verbosity.env")
+	os.WriteFile(envFile, []byte(VerbosityEnvVar+"=db=TR\n"+DebugEnvVar+"=true\n"), 0644)
+	t.Setenv(VerbosityEnvVar, "")
+	t.Setenv(DebugEnvVar, "")
+
+	HandleVerbositySignal(syscall.SIGHUP, envFile)
+	defer HandleSignal(syscall.SIGHUP, nil)
+
+	processSignal(syscall.SIGHUP)
+
+	if !IsDebug() || Verbosity("db") != LogLevelTrace || Verbosity("") != LogLevelDebug {
+		t.Errorf("got %v %s", IsDebug(), Verbosity("db"))
+	}
+
+	HandleVerbositySignal(syscall.SIGHUP, "")
+	processSignal(syscall.SIGHUP)
+	if IsDebug() {
+		t.Errorf("debug mode is not toggled")
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func inlinedCallStack() []CallStackFrame {
+	return GetCallStack(0)
+}
+
+func TestCallStack(t *testing.T) {
+	stack := inlinedCallStack()
+	if len(stack) < 2 || !strings.HasSuffix(stack[0].FuncName, ".inlinedCallStack") || !strings.HasSuffix(stack[1].FuncName, ".TestCallStack") {
+		t.Fatalf("got\n%s", FormatCallStack(stack))
+	}
+
+	stack = GetCallStackEx(0, &CallStackOptions{SkipRuntime: true})
+	if last := stack[len(stack)-1].FuncName; !strings.HasSuffix(last, ".TestCallStack") {
+		t.Errorf(`got "%s"`, last)
+	}
+
+	stack = GetCallStackEx(0, &CallStackOptions{SkipPrefixes: []string{"github.com/alrusov/misc."}, SkipStdlib: true})
+	if len(stack) != 0 {
+		t.Errorf("got\n%s", FormatCallStack(stack))
+	}
+
+	if stack = GetCallStackEx(0, &CallStackOptions{MaxDepth: 1}); len(stack) != 1 {
+		t.Errorf("got %d frames", len(stack))
+	}
+
+	if s := GetFuncName(0, true); s != "misc.TestCallStack" {
+		t.Errorf(`got "%s"`, s)
+	}
+
+	if s := GetFuncName(0, false); s != "misc.TestCallStack" {
+		t.Errorf(`got "%s"`, s)
+	}
+
+	frame := []CallStackFrame{{FuncName: "github.com/alrusov/misc/sub.F", FileName: "/src/misc/sub/f.go", Line: 10}}
+
+	for _, df := range []struct {
+		format   *CallStackFormat
+		expected string
+	}{
+		{nil, "github.com/alrusov/misc/sub.F" + EOS + "\t/src/misc/sub/f.go:10"},
+		{&CallStackFormat{FileNames: FileNameShort, ShortFuncNames: true, SingleLine: true}, "sub.F f.go:10"},
+		{&CallStackFormat{FileNames: FileNameModule, SingleLine: true}, "github.com/alrusov/misc/sub.F sub/f.go:10"},
+	} {
+		if s := FormatCallStackEx(frame, df.format); s != df.expected {
+			t.Errorf(`got "%s", expected "%s"`, s, df.expected)
+		}
+	}
+}
+
+// legacyGetCallStack -- the previous implementation for the benchmark comparison
+func legacyGetCallStack(shift int) []CallStackFrame {
+	var ret []CallStackFrame
+
+	pc := make([]uintptr, 500)
+	n := runtime.Callers(2+shift, pc)
+	for i := 0; i < n; i++ {
+		fn := runtime.FuncForPC(pc[i])
+		frame := CallStackFrame{FuncName: fn.Name()}
+		frame.FileName, frame.Line = fn.FileLine(pc[i])
+		ret = append(ret, frame)
+	}
+
+	return ret
+}
+
+func BenchmarkGetCallStackLegacy(b *testing.B) {
+	for b.Loop() {
+		legacyGetCallStack(0)
+	}
+}
+
+func BenchmarkGetCallStack(b *testing.B) {
+	for b.Loop() {
+		GetCallStack(0)
+	}
+}
+
+func BenchmarkGetCallStackFiltered(b *testing.B) {
+	opts := &CallStackOptions{SkipRuntime: true, MaxDepth: 8}
+	for b.Loop() {
+		GetCallStackEx(0, opts)
+	}
+}
+
+func BenchmarkGetFuncName(b *testing.B) {
+	for b.Loop() {
+		GetFuncName(0, true)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestNetInterfaces(t *testing.T) {
+	ifaces, err := RefreshNetInterfaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loopback := netip.Addr{}
+	for _, iface := range ifaces {
+		for _, p := range iface.Prefixes {
+			if !p.IsValid() || p.Addr().Is4In6() {
+				t.Errorf("%s: bad prefix %s", iface.Name, p)
+			}
+			if iface.Loopback && p.Addr().Is4() {
+				loopback = p.Addr()
+			}
+		}
+	}
+
+	if !loopback.IsValid() {
+		t.Skip("no IPv4 loopback interface")
+	}
+
+	if ok, err := IsMyAddr(loopback); err != nil || !ok {
+		t.Errorf("%s: got %v, %v", loopback, ok, err)
+	}
+
+	if ok, err := IsMyIP(loopback.String()); err != nil || !ok {
+		t.Errorf("%s: got %v, %v", loopback, ok, err)
+	}
+
+	if ok, _ := IsMyAddr(netip.MustParseAddr("192.0.2.250")); ok {
+		t.Errorf("TEST-NET address is not expected to be local")
+	}
+
+	if ok, err := IsMyIP("not an address"); ok || err != nil {
+		t.Errorf("got %v, %v", ok, err)
+	}
+
+	ips, err := GetMyIPs()
+	if err != nil || !ips[loopback.String()] {
+		t.Errorf("got %v, %v", ips, err)
+	}
+
+	addrs, err := MyAddrs(&NetAddrFilter{IPv4: true, Loopback: true})
+	if err != nil || !slices.ContainsFunc(addrs, func(p netip.Prefix) bool { return p.Addr() == loopback }) {
+		t.Errorf("got %v, %v", addrs, err)
+	}
+
+	addrs, _ = MyAddrs(&NetAddrFilter{IPv6: true, Private: true, Global: true})
+	for _, p := range addrs {
+		if !p.Addr().Is6() || p.Addr().IsLoopback() || p.Addr().IsLinkLocalUnicast() {
+			t.Errorf("unexpected %s", p)
+		}
+	}
+
+	f := &NetAddrFilter{LinkLocal: true}
+	if !f.Match(&NetInterface{}, netip.MustParseAddr("fe80::1")) || f.Match(&NetInterface{}, netip.MustParseAddr("10.0.0.1")) {
+		t.Errorf("link-local filter failed")
+	}
+
+	if s := NetAddrScopeOf(netip.MustParseAddr("172.16.1.1")); s != NetScopePrivate {
+		t.Errorf("got %d", s)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
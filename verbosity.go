@@ -0,0 +1,201 @@
+package misc
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+const (
+	// DebugEnvVar -- environment variable with the debug mode flag ("1", "true" etc.)
+	DebugEnvVar = "MISC_DEBUG"
+
+	// VerbosityEnvVar -- environment variable with the verbosity spec, see SetVerbositySpec
+	VerbosityEnvVar = "MISC_VERBOSITY"
+)
+
+var (
+	verbosityMutex       sync.RWMutex
+	verbosityDefault     *LogLevel // nil -- LogLevelDebug in the debug mode, LogLevelInfo otherwise
+	verbosityLevels      = make(map[string]LogLevel, 8)
+	verbositySignalMutex sync.Mutex
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetVerbosity -- set the maximal level of the facility messages. Facility "" or "*" sets the default level.
+// Facilities are hierarchical: the level of "db" is used for "db.pool" if "db.pool" is not set
+func SetVerbosity(facility string, level LogLevel) {
+	verbosityMutex.Lock()
+	if facility == "" || facility == "*" {
+		verbosityDefault = &level
+	} else {
+		verbosityLevels[facility] = level
+	}
+	verbosityMutex.Unlock()
+
+	// after unlocking, the Logger may check the verbosity
+	logMessage(LogLevelNotice, `Verbosity of "%s" is set to %s`, facility, level)
+}
+
+// ResetVerbosity -- remove the facility level, "" or "*" restores the automatic default level
+func ResetVerbosity(facility string) {
+	verbosityMutex.Lock()
+	defer verbosityMutex.Unlock()
+
+	if facility == "" || facility == "*" {
+		verbosityDefault = nil
+	} else {
+		delete(verbosityLevels, facility)
+	}
+}
+
+// Verbosity -- maximal level of the facility messages
+func Verbosity(facility string) LogLevel {
+	verbosityMutex.RLock()
+	defer verbosityMutex.RUnlock()
+
+	for f := facility; f != ""; {
+		if level, exists := verbosityLevels[f]; exists {
+			return level
+		}
+
+		i := strings.LastIndexByte(f, '.')
+		if i < 0 {
+			break
+		}
+		f = f[:i]
+	}
+
+	if verbosityDefault != nil {
+		return *verbosityDefault
+	}
+
+	if IsDebug() {
+		return LogLevelDebug
+	}
+
+	return LogLevelInfo
+}
+
+// VerbosityLevels -- explicitly set levels, the default level has the "*" key
+func VerbosityLevels() map[string]LogLevel {
+	verbosityMutex.RLock()
+	defer verbosityMutex.RUnlock()
+
+	levels := maps.Clone(verbosityLevels)
+	if verbosityDefault != nil {
+		levels["*"] = *verbosityDefault
+	}
+	return levels
+}
+
+// IsLogEnabled -- should the message of the facility with the level be written?
+func IsLogEnabled(facility string, level LogLevel) bool {
+	return level <= Verbosity(facility)
+}
+
+// IsDebugFor -- is the debug output of the facility enabled?
+func IsDebugFor(facility string) bool {
+	return IsLogEnabled(facility, LogLevelDebug)
+}
+
+// LogMessage -- write the message through the Logger if it is enabled by the facility verbosity
+func LogMessage(facility string, level LogLevel, message string, params ...any) {
+	if IsLogEnabled(facility, level) {
+		GetLogger()(facility, level.String(), message, params...)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetVerbositySpec -- replace all levels by the spec: "IN,db=DE,http.client=TR". The item without the facility sets the default level,
+// levels are the two-letter names (see LogLevel.String). Empty spec resets all levels
+func SetVerbositySpec(spec string) error {
+	levels := make(map[string]LogLevel, 8)
+	var def *LogLevel
+
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		facility, name, ok := strings.Cut(item, "=")
+		if !ok {
+			facility, name = "", item
+		}
+		facility = strings.TrimSpace(facility)
+
+		level, ok := ParseLogLevel(strings.TrimSpace(name))
+		if !ok {
+			return fmt.Errorf(`verbosity "%s": unknown level "%s"`, spec, name)
+		}
+
+		if facility == "" || facility == "*" {
+			def = &level
+		} else {
+			levels[facility] = level
+		}
+	}
+
+	verbosityMutex.Lock()
+	verbosityDefault = def
+	verbosityLevels = levels
+	verbosityMutex.Unlock()
+
+	logMessage(LogLevelNotice, `Verbosity is set to "%s"`, spec)
+	return nil
+}
+
+// VerbosityFromEnv -- set the debug mode and the levels from DebugEnvVar and VerbosityEnvVar, missing variables don't change the current state
+func VerbosityFromEnv() error {
+	if s, exists := os.LookupEnv(DebugEnvVar); exists {
+		mode, err := strconv.ParseBool(strings.TrimSpace(s))
+		if err != nil {
+			return fmt.Errorf("%s: %w", DebugEnvVar, err)
+		}
+		SetDebugMode(mode)
+	}
+
+	if spec, exists := os.LookupEnv(VerbosityEnvVar); exists {
+		return SetVerbositySpec(spec)
+	}
+
+	return nil
+}
+
+// LoadVerbosity -- load the env file by LoadEnv and apply VerbosityFromEnv
+func LoadVerbosity(envFile string) error {
+	if err := LoadEnv(envFile); err != nil {
+		return err
+	}
+
+	return VerbosityFromEnv()
+}
+
+// HandleVerbositySignal -- reload the verbosity from the env file when the signal is received. If envFile is "" the signal toggles the debug mode
+func HandleVerbositySignal(sig os.Signal, envFile string) {
+	HandleSignal(sig,
+		func(os.Signal) {
+			verbositySignalMutex.Lock()
+			defer verbositySignalMutex.Unlock()
+
+			if envFile == "" {
+				SetDebugMode(!forcedDebugMode.Load())
+				return
+			}
+
+			if err := LoadVerbosity(envFile); err != nil {
+				logMessage(LogLevelError, "Verbosity reload: %s", err)
+			}
+		},
+	)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//